@@ -0,0 +1,45 @@
+package simpleini
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// fieldAliases returns the alternative names of a field, given by the alias
+// options of its ini tag, as in ini:"max_conns,alias=maxconnections".
+func fieldAliases(field reflect.StructField) []string {
+	return tagOptionValues(field, "alias")
+}
+
+// findAlias returns the struct field with the key as one of its aliases.
+func (d *decoder) findAlias(fieldMap map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	for _, field := range fieldMap {
+		if d.isAlias(field, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// isAlias checks if the key is one of the aliases of the field.
+func (d *decoder) isAlias(field reflect.StructField, key string) bool {
+	return slices.ContainsFunc(fieldAliases(field), func(alias string) bool {
+		return namesEqual(d.opts.caseMode, alias, key)
+	})
+}
+
+// checkDeprecated warns about a key of a field tagged deprecated, as in
+// deprecated:"use max_conns". If the field has aliases, only they are
+// deprecated, and the tag value says what to use instead.
+func (d *decoder) checkDeprecated(field reflect.StructField, st *lineState, lineNumber int) {
+	reason, ok := field.Tag.Lookup("deprecated")
+	if !ok || len(fieldAliases(field)) > 0 && !d.isAlias(field, st.key) {
+		return
+	}
+	message := fmt.Sprintf("key '%s' is deprecated", st.key)
+	if reason != "" {
+		message += ": " + reason
+	}
+	d.warn(Warning{Line: lineNumber, Section: st.section, Key: st.key, Message: message})
+}
@@ -0,0 +1,111 @@
+package simpleini
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type AliasPool struct {
+	MaxConns int    `ini:"max_conns,alias=maxconnections,alias=max_connections" deprecated:"use max_conns"`
+	Timeout  int    `ini:",alias=wait"`
+	Legacy   string `deprecated:"no longer used"`
+}
+
+type AliasConfig struct {
+	Pool AliasPool
+}
+
+func TestParse_Aliases(t *testing.T) {
+	iniContent := `
+[pool]
+maxconnections = 10
+wait = 5
+`
+
+	config := AliasConfig{}
+	if errors := Parse(strings.NewReader(iniContent), &config); errors != nil {
+		t.Fatalf("Failed to parse INI with aliases: %v", errors)
+	}
+	if config.Pool.MaxConns != 10 || config.Pool.Timeout != 5 {
+		t.Errorf("Expected aliases to set their fields, got %+v", config.Pool)
+	}
+}
+
+func TestParse_DeprecatedWarnings(t *testing.T) {
+	iniContent := `
+[pool]
+max_conns = 1
+MAX_CONNECTIONS = 2
+timeout = 3
+legacy = x
+`
+
+	var warnings []Warning
+	config := AliasConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithWarningHandler(func(w Warning) {
+		warnings = append(warnings, w)
+	}))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	expected := []Warning{
+		{Line: 4, Section: "pool", Key: "max_connections", Message: "key 'max_connections' is deprecated: use max_conns"},
+		{Line: 6, Section: "pool", Key: "legacy", Message: "key 'legacy' is deprecated: no longer used"},
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected warnings %+v, got %+v", expected, warnings)
+	}
+	if config.Pool.MaxConns != 2 {
+		t.Errorf("Expected the alias to set the field, got %+v", config.Pool)
+	}
+}
+
+func TestParse_AliasDuplicates(t *testing.T) {
+	iniContent := "[pool]\nmax_conns = 1\nmaxconnections = 2\n"
+
+	config := AliasConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithDuplicateKeys(DuplicateError))
+	if len(errors) != 1 || errors[0].Error() != "duplicate key 'maxconnections' at line 3, first set at line 2" {
+		t.Errorf("Expected an alias to repeat the key of its field, got %v", errors)
+	}
+}
+
+func TestParse_AliasesCaseSensitive(t *testing.T) {
+	config := AliasConfig{}
+	if errors := Parse(strings.NewReader("[pool]\nwait = 5\n"), &config, WithCaseMode(CaseSensitive)); errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	errors := Parse(strings.NewReader("[pool]\nWait = 5\n"), &config, WithCaseMode(CaseSensitive))
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "no matching field found for key 'Wait'") {
+		t.Errorf("Expected aliases to match case exactly, got %v", errors)
+	}
+}
+
+func TestWrite_CanonicalNames(t *testing.T) {
+	config := AliasConfig{Pool: AliasPool{MaxConns: 10, Timeout: 5}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, &config); err != nil {
+		t.Fatalf("Failed to write INI: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "max_conns = 10") || !strings.Contains(output, "timeout = 5") {
+		t.Errorf("Expected the canonical names, got:\n%s", output)
+	}
+	if strings.Contains(output, "alias") || strings.Contains(output, "maxconnections") || strings.Contains(output, "wait") {
+		t.Errorf("Expected no aliases in the output, got:\n%s", output)
+	}
+}
+
+func TestTagOptionValues(t *testing.T) {
+	field := reflect.TypeFor[AliasPool]().Field(0)
+	expected := []string{"maxconnections", "max_connections"}
+	if values := tagOptionValues(field, "alias"); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %q, got %q", expected, values)
+	}
+	if values := tagOptionValues(field, "secret"); values != nil {
+		t.Errorf("Expected no values, got %q", values)
+	}
+}
@@ -0,0 +1,43 @@
+package simpleini
+
+import "strings"
+
+// CaseMode controls how the case of section and key names is handled.
+type CaseMode int
+
+const (
+	// CaseInsensitive lowercases section and key names, so names that differ
+	// only in case are the same. This is the default.
+	CaseInsensitive CaseMode = iota
+	// CasePreserving keeps the spelling of names for error messages and
+	// documents, but still matches them to fields ignoring case.
+	CasePreserving
+	// CaseSensitive keeps the spelling of names and matches them exactly. A
+	// key must be spelled like the ini tag of its field, or like the name Write
+	// gives a field without one.
+	CaseSensitive
+)
+
+// WithCaseMode sets how the case of section and key names is handled.
+func WithCaseMode(mode CaseMode) Option {
+	return func(o *options) {
+		o.caseMode = mode
+	}
+}
+
+// foldName returns the name used to tell sections and keys apart, which
+// ignores case unless names are case-sensitive.
+func (d *decoder) foldName(name string) string {
+	if d.opts.caseMode == CaseSensitive {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// namesEqual checks if two section or key names are the same in the mode.
+func namesEqual(mode CaseMode, a, b string) bool {
+	if mode == CaseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
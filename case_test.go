@@ -0,0 +1,123 @@
+package simpleini
+
+import (
+	"strings"
+	"testing"
+)
+
+type CaseServer struct {
+	Host     string
+	MaxConns int
+	Mode     string `ini:"Mode"`
+}
+
+type CaseConfig struct {
+	Name   string
+	Server CaseServer
+}
+
+func TestParse_CaseModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     CaseMode
+		content  string
+		expected string
+	}{
+		{"insensitive", CaseInsensitive, "NAME = x\n[SERVER]\nMax_Conns = 4\n", ""},
+		{"insensitive unknown key", CaseInsensitive, "Bogus_Key = x\n", "error at line 1: no matching field found for key 'bogus_key'"},
+		{"preserving", CasePreserving, "NAME = x\n[SERVER]\nMax_Conns = 4\nmode = m\n", ""},
+		{"preserving unknown key", CasePreserving, "Bogus_Key = x\n", "error at line 1: no matching field found for key 'Bogus_Key'"},
+		{"sensitive", CaseSensitive, "name = x\n[server]\nmax_conns = 4\nMode = m\n", ""},
+		{"sensitive key", CaseSensitive, "Name = x\n", "error at line 1: no matching field found for key 'Name'"},
+		{"sensitive tag", CaseSensitive, "[server]\nmode = m\n", "error at line 2: no matching field found for key 'mode'"},
+		{"sensitive section", CaseSensitive, "[Server]\nhost = h\n", "error at line 2: no matching field found for section 'Server'"},
+	}
+
+	for _, test := range tests {
+		config := CaseConfig{}
+		errors := Parse(strings.NewReader(test.content), &config, WithCaseMode(test.mode))
+		if test.expected == "" {
+			if errors != nil {
+				t.Errorf("%s: failed to parse INI: %v", test.name, errors)
+			} else if config.Name != "x" || config.Server.MaxConns != 4 {
+				t.Errorf("%s: expected the values to be set, got %+v", test.name, config)
+			}
+			continue
+		}
+		if len(errors) != 1 || errors[0].Error() != test.expected {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expected, errors)
+		}
+	}
+}
+
+func TestParse_CasePreservingReferences(t *testing.T) {
+	iniContent := `
+[Worker.A]
+Queue = default
+
+[worker.b : worker.a]
+concurrency = 2
+
+[worker.c]
+queue = ${WORKER.a.QUEUE}-copy
+`
+
+	config := InheritConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithCaseMode(CasePreserving))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if config.Worker["b"].Queue != "default" || config.Worker["c"].Queue != "default-copy" {
+		t.Errorf("Expected names to match ignoring case, got %+v", config.Worker)
+	}
+}
+
+func TestParse_CaseSensitiveMapKeys(t *testing.T) {
+	iniContent := `
+[worker.A]
+queue = upper
+
+[worker.a]
+queue = lower
+`
+
+	config := InheritConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithCaseMode(CaseSensitive))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if len(config.Worker) != 2 || config.Worker["A"].Queue != "upper" || config.Worker["a"].Queue != "lower" {
+		t.Errorf("Expected sections differing in case to be distinct, got %+v", config.Worker)
+	}
+}
+
+func TestParseDocument_CaseModes(t *testing.T) {
+	content := "[Server]\nHost = h\n"
+
+	doc, err := ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	if doc.Sections[1].Name != "server" || doc.Section("SERVER").Key("HOST") == nil {
+		t.Errorf("Expected lowercased names, got %+v", doc.Sections[1])
+	}
+
+	doc, err = ParseDocument(strings.NewReader(content), WithCaseMode(CasePreserving))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	if doc.Sections[1].Name != "Server" || doc.Section("server").Key("host").Name != "Host" {
+		t.Errorf("Expected preserved names matched ignoring case, got %+v", doc.Sections[1])
+	}
+
+	doc, err = ParseDocument(strings.NewReader(content), WithCaseMode(CaseSensitive))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	if doc.Section("server") != nil || doc.Section("Server").Key("host") != nil {
+		t.Errorf("Expected names to match exactly, got %+v", doc.Sections[1])
+	}
+	if added := doc.AddSection("server"); added == doc.Sections[1] || added.Key("Host") != nil {
+		t.Errorf("Expected a new section for a name differing in case, got %+v", added)
+	}
+}
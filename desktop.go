@@ -0,0 +1,111 @@
+package simpleini
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// LocalizedString is a value with translations, as written by the
+// freedesktop.org desktop entry keys Name=Files and Name[de]=Dateien. The
+// unlocalized value is stored in Default and the translations in Locales,
+// keyed by locale.
+type LocalizedString struct {
+	Default string
+	Locales map[string]string
+}
+
+var localizedStringType = reflect.TypeOf(LocalizedString{})
+
+// UnmarshalText sets the default value.
+func (s *LocalizedString) UnmarshalText(text []byte) error {
+	s.Default = string(text)
+	return nil
+}
+
+// Set sets the translation for the locale, or the default value if the locale
+// is empty.
+func (s *LocalizedString) Set(locale, value string) {
+	if locale == "" {
+		s.Default = value
+		return
+	}
+	if s.Locales == nil {
+		s.Locales = make(map[string]string)
+	}
+	s.Locales[locale] = value
+}
+
+// Get returns the translation for a locale of the form
+// lang_COUNTRY.ENCODING@MODIFIER, falling back as described by the desktop
+// entry specification: lang_COUNTRY@MODIFIER, lang_COUNTRY, lang@MODIFIER,
+// lang and finally the default value.
+func (s LocalizedString) Get(locale string) string {
+	lang, modifier, _ := strings.Cut(locale, "@")
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, country, _ := strings.Cut(lang, "_")
+
+	var candidates []string
+	if country != "" && modifier != "" {
+		candidates = append(candidates, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		candidates = append(candidates, lang+"_"+country)
+	}
+	if modifier != "" {
+		candidates = append(candidates, lang+"@"+modifier)
+	}
+	candidates = append(candidates, lang)
+
+	for _, candidate := range candidates {
+		if value, ok := s.Locales[candidate]; ok {
+			return value
+		}
+	}
+	return s.Default
+}
+
+// String returns the default value.
+func (s LocalizedString) String() string {
+	return s.Default
+}
+
+// isLocalizedMap checks if the type is a map of strings keyed by locale, which
+// holds the variants of a localized key.
+func isLocalizedMap(t reflect.Type) bool {
+	t = indirectType(t)
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+}
+
+// isLocalizedField checks if a field of the type is written as localized keys:
+// a LocalizedString, or a map of strings when localized keys are enabled.
+func isLocalizedField(t reflect.Type, localizedKeys bool) bool {
+	return indirectType(t) == localizedStringType || (localizedKeys && isLocalizedMap(t))
+}
+
+// localizedEntries returns the default value and the sorted translations of a
+// LocalizedString or a map of strings keyed by locale, with the default value
+// stored under the empty key.
+func localizedEntries(v reflect.Value) (def string, locales []string, values map[string]string) {
+	values = make(map[string]string)
+	if v.Type() == localizedStringType {
+		s := v.Interface().(LocalizedString)
+		def = s.Default
+		for locale, value := range s.Locales {
+			values[locale] = value
+		}
+	} else {
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = iter.Value().String()
+		}
+		def = values[""]
+		delete(values, "")
+	}
+
+	for locale := range values {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return def, locales, values
+}
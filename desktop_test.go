@@ -0,0 +1,44 @@
+package simpleini
+
+import "testing"
+
+func TestLocalizedString_Get(t *testing.T) {
+	s := LocalizedString{
+		Default: "Files",
+		Locales: map[string]string{
+			"de":       "Dateien",
+			"de_AT":    "Dateien (AT)",
+			"sr@latin": "Datoteke",
+		},
+	}
+
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"de", "Dateien"},
+		{"de_AT", "Dateien (AT)"},
+		{"de_AT.UTF-8", "Dateien (AT)"},
+		{"de_CH", "Dateien"},
+		{"sr_RS@latin", "Datoteke"},
+		{"sr_RS", "Files"},
+		{"fr", "Files"},
+		{"", "Files"},
+	}
+
+	for _, test := range tests {
+		result := s.Get(test.locale)
+		if result != test.expected {
+			t.Errorf("Get(%q) = %q; expected %q", test.locale, result, test.expected)
+		}
+	}
+}
+
+func TestLocalizedString_Set(t *testing.T) {
+	var s LocalizedString
+	s.Set("", "Files")
+	s.Set("de", "Dateien")
+	if s.Default != "Files" || s.Locales["de"] != "Dateien" || s.String() != "Files" {
+		t.Errorf("Unexpected LocalizedString: %+v", s)
+	}
+}
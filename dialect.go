@@ -0,0 +1,98 @@
+package simpleini
+
+// Dialect is a preset of options for INI files produced by other tools.
+type Dialect int
+
+const (
+	// DialectDefault is the syntax understood by Parse without any options.
+	DialectDefault Dialect = iota
+	// DialectPHP accepts PHP-style array keys such as key[] = a, key[0] = b and
+	// key[name] = c, mapping them onto slice, array and map fields. Inline
+	// comments start with ';'.
+	DialectPHP
+	// DialectGit reads and writes git-config style files. Section headers may have
+	// a quoted subsection, as in [remote "origin"], which selects the entry of a
+	// map field such as Remote map[string]RemoteConfig. Names may contain dashes
+	// and are matched ignoring case, dashes and underscores, so defaultBranch
+	// matches a DefaultBranch field. A key without a value is true, booleans
+	// accept yes, no, on and off, and [include] path = file includes another
+	// file. Values continue with a trailing backslash, so keys can be indented,
+	// and inline comments start with ';' or '#'. Write indents keys, names
+	// untagged fields in camelCase and writes subsection headers.
+	DialectGit
+	// DialectSystemd reads and writes systemd unit files. Each assignment to a
+	// slice field appends an element and an empty assignment clears it. Key case
+	// is preserved, booleans accept yes, no, on and off, values are used verbatim
+	// without unquoting or environment variable expansion, and a trailing
+	// backslash joins lines with a space.
+	// Write uses Key=Value lines, one per slice element, and names untagged
+	// fields and sections after the Go field, as in [Service] and ExecStart.
+	DialectSystemd
+	// DialectDesktop reads and writes freedesktop.org desktop entries. Section
+	// names may contain spaces, as in [Desktop Entry], and are matched ignoring
+	// case and spaces, so a DesktopEntry field matches. Localized keys such as
+	// Name[de] = Dateien set the entries of a LocalizedString field, or of a
+	// map[string]string field where the unlocalized key is stored under "".
+	// Slices are split on ';', which may be escaped as \;. Key case is
+	// preserved and values are used verbatim.
+	// Write uses Key=Value lines, ends lists with ';' and names untagged fields
+	// and sections after the Go field.
+	DialectDesktop
+)
+
+// WithDialect applies the preset options for the dialect. Options given after
+// it override the preset.
+func WithDialect(dialect Dialect) Option {
+	return func(o *options) {
+		switch dialect {
+		case DialectPHP:
+			o.arrayKeys = true
+			o.inlineComments = []string{";"}
+		case DialectGit:
+			o.subsections = true
+			o.looseNames = true
+			o.valuelessKeys = true
+			o.extendedBooleans = true
+			o.includeSection = true
+			o.indentKeys = true
+			o.nameFunc = pascalToCamel
+			o.continuation = ContinuationBackslash
+			o.inlineComments = []string{";", "#"}
+		case DialectSystemd:
+			o.caseMode = CasePreserving
+			o.looseNames = true
+			o.appendSlices = true
+			o.extendedBooleans = true
+			o.rawValues = true
+			o.expandEnv = false
+			o.tightDelimiter = true
+			o.continuation = ContinuationBackslash
+			o.backslashJoin = " "
+			o.inlineComments = nil
+			o.nameFunc = func(fieldName string) string { return fieldName }
+		case DialectDesktop:
+			o.freeformSections = true
+			o.caseMode = CasePreserving
+			o.looseNames = true
+			o.arrayKeys = true
+			o.localizedKeys = true
+			o.rawValues = true
+			o.expandEnv = false
+			o.tightDelimiter = true
+			o.sliceSeparator = ";"
+			o.terminatedLists = true
+			o.continuation = 0
+			o.inlineComments = nil
+			o.nameFunc = func(fieldName string) string { return fieldName }
+		}
+	}
+}
+
+// WithArrayKeys enables PHP-style array keys. key[] appends to a slice or array
+// field, key[N] sets element N of a slice or array field and key[name] sets an
+// entry of a map field.
+func WithArrayKeys() Option {
+	return func(o *options) {
+		o.arrayKeys = true
+	}
+}
@@ -0,0 +1,567 @@
+package simpleini
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type GitUserConfig struct {
+	Name  string
+	Email string
+}
+
+type GitCoreConfig struct {
+	Bare         bool
+	IgnoreCase   *bool
+	AutoCRLF     string `ini:"autocrlf"`
+	FileMode     bool
+	SharedRepo   bool
+	HooksPath    string
+	ExcludesFile string
+}
+
+type GitRemoteConfig struct {
+	URL   string `ini:"url"`
+	Fetch string
+}
+
+type GitBranchConfig struct {
+	Remote string
+	Merge  string
+}
+
+type GitConfig struct {
+	User   GitUserConfig
+	Core   GitCoreConfig
+	Remote map[string]GitRemoteConfig
+	Branch map[string]*GitBranchConfig
+}
+
+func TestParse_GitDialect(t *testing.T) {
+	iniContent := `
+# Git configuration
+[user]
+	name = Jane Doe
+	email = jane@example.com ; work address
+[core]
+	bare = false
+	ignoreCase = yes
+	fileMode = off
+	sharedRepo
+	autocrlf = input
+	hooks-path = "/opt/hooks"
+	excludesFile = ~/.gitignore \
+		-global
+[remote "origin"]
+	url = https://example.com/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+[branch "feature/x.y"]
+	remote = origin
+	merge = refs/heads/feature/x.y
+[Branch "Main"]
+	remote = upstream
+`
+
+	config := GitConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithDialect(DialectGit))
+	if errors != nil {
+		t.Fatalf("Failed to parse git config: %v", errors)
+	}
+
+	if config.User.Name != "Jane Doe" || config.User.Email != "jane@example.com" {
+		t.Errorf("Unexpected user: %+v", config.User)
+	}
+	if config.Core.Bare || config.Core.IgnoreCase == nil || !*config.Core.IgnoreCase || config.Core.FileMode || !config.Core.SharedRepo {
+		t.Errorf("Unexpected core booleans: %+v", config.Core)
+	}
+	if config.Core.AutoCRLF != "input" || config.Core.HooksPath != "/opt/hooks" {
+		t.Errorf("Unexpected core values: %+v", config.Core)
+	}
+	if config.Core.ExcludesFile != "~/.gitignore\n-global" {
+		t.Errorf("Unexpected continued value: %q", config.Core.ExcludesFile)
+	}
+	expectedRemote := map[string]GitRemoteConfig{
+		"origin": {URL: "https://example.com/repo.git", Fetch: "+refs/heads/*:refs/remotes/origin/*"},
+	}
+	if !reflect.DeepEqual(config.Remote, expectedRemote) {
+		t.Errorf("Unexpected remotes: %+v", config.Remote)
+	}
+	if len(config.Branch) != 2 || config.Branch["feature/x.y"].Merge != "refs/heads/feature/x.y" || config.Branch["Main"].Remote != "upstream" {
+		t.Errorf("Unexpected branches: %+v", config.Branch)
+	}
+}
+
+func TestParse_GitDialectInclude(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.inc"), []byte("[user]\n\temail = included@example.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+	mainFile := filepath.Join(dir, "config")
+	mainContent := `
+[user]
+	name = Jane
+[include]
+	path = user.inc
+[core]
+	bare = true
+`
+	if err := os.WriteFile(mainFile, []byte(mainContent), 0644); err != nil {
+		t.Fatalf("Failed to write main file: %v", err)
+	}
+
+	config := GitConfig{}
+	errors := ParseFile(mainFile, &config, WithDialect(DialectGit))
+	if errors != nil {
+		t.Fatalf("Failed to parse git config with include: %v", errors)
+	}
+
+	if config.User.Name != "Jane" || config.User.Email != "included@example.com" {
+		t.Errorf("Unexpected user: %+v", config.User)
+	}
+	if !config.Core.Bare {
+		t.Error("Expected core.bare to be set after the include")
+	}
+}
+
+func TestParse_GitDialectErrors(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{`[remote "origin]`, `invalid section name at line 1: remote "origin`},
+		{`[bad name "x"]`, "invalid section name at line 1: bad name"},
+		{"[core]\nbare = maybe", "error at line 2: invalid value for field type bool: maybe"},
+		{"[remote]\nurl = x", "error at line 2: field for section 'remote' is not a struct"},
+	}
+
+	for _, test := range tests {
+		config := GitConfig{}
+		errors := Parse(strings.NewReader(test.content), &config, WithDialect(DialectGit))
+		if errors == nil || !strings.Contains(errors[0].Error(), test.expected) {
+			t.Errorf("Expected error %q for %q, got %v", test.expected, test.content, errors)
+		}
+	}
+}
+
+func TestParse_DottedSectionMap(t *testing.T) {
+	iniContent := `
+[remote.origin]
+url = https://example.com/repo.git
+
+[branch.main]
+remote = origin
+`
+
+	config := GitConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse dotted section map: %v", errors)
+	}
+
+	if config.Remote["origin"].URL != "https://example.com/repo.git" {
+		t.Errorf("Unexpected remotes: %+v", config.Remote)
+	}
+	if config.Branch["main"] == nil || config.Branch["main"].Remote != "origin" {
+		t.Errorf("Unexpected branches: %+v", config.Branch)
+	}
+}
+
+func TestWrite_GitDialect(t *testing.T) {
+	ignoreCase := true
+	config := &GitConfig{
+		User: GitUserConfig{Name: "Jane Doe", Email: "jane@example.com"},
+		Core: GitCoreConfig{IgnoreCase: &ignoreCase, AutoCRLF: "input"},
+		Remote: map[string]GitRemoteConfig{
+			"upstream": {URL: "https://example.com/upstream.git"},
+			"origin":   {URL: "https://example.com/repo.git", Fetch: "+refs/heads/*:refs/remotes/origin/*"},
+		},
+		Branch: map[string]*GitBranchConfig{
+			`say "hi"`: {Remote: "origin"},
+			"skipped":  nil,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, config, WithDialect(DialectGit)); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+
+	expected := `
+[user]
+	name = Jane Doe
+	email = jane@example.com
+
+[core]
+	bare = false
+	ignoreCase = true
+	autocrlf = input
+	fileMode = false
+	sharedRepo = false
+	hooksPath = 
+	excludesFile = 
+
+[remote "origin"]
+	url = https://example.com/repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+
+[remote "upstream"]
+	url = https://example.com/upstream.git
+	fetch = 
+
+[branch "say \"hi\""]
+	remote = origin
+	merge = 
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	parsed := GitConfig{}
+	if errors := Parse(&buf, &parsed, WithDialect(DialectGit)); errors != nil {
+		t.Fatalf("Failed to parse written git config: %v", errors)
+	}
+	if !reflect.DeepEqual(parsed.Remote, config.Remote) || parsed.Branch[`say "hi"`].Remote != "origin" {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+}
+
+func TestWrite_GitDialectTrailingBackslash(t *testing.T) {
+	type Config struct {
+		Core struct {
+			Path  string
+			Other string
+		}
+	}
+
+	config := &Config{}
+	config.Core.Path = `C:\dir\`
+	config.Core.Other = "x"
+
+	var buf bytes.Buffer
+	if err := Write(&buf, config, WithDialect(DialectGit)); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+	expected := "\n[core]\n\tpath = \"C:\\\\dir\\\\\"\n\tother = x\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	parsed := &Config{}
+	if errors := Parse(&buf, parsed, WithDialect(DialectGit)); errors != nil {
+		t.Fatalf("Failed to parse written git config: %v", errors)
+	}
+	if !reflect.DeepEqual(parsed, config) {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+}
+
+func TestWrite_DottedSectionMap(t *testing.T) {
+	config := &GitConfig{
+		Remote: map[string]GitRemoteConfig{"origin": {URL: "u"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, config); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if !strings.Contains(buf.String(), "\n[remote.origin]\nurl = u\nfetch = \n") {
+		t.Errorf("Expected dotted map section, got:\n%s", buf.String())
+	}
+}
+
+type UnitSection struct {
+	Description string
+	After       []string
+	Wants       []string
+}
+
+type ServiceSection struct {
+	Type          string
+	ExecStartPre  []string
+	ExecStart     string
+	Environment   []string
+	Restart       string
+	RestartSec    int
+	RemainAfterOK *bool `ini:"RemainAfterExit"`
+}
+
+type InstallSection struct {
+	WantedBy []string
+}
+
+type UnitFile struct {
+	Unit    UnitSection
+	Service ServiceSection
+	Install InstallSection
+}
+
+func TestParse_SystemdDialect(t *testing.T) {
+	iniContent := `
+# Example unit
+[Unit]
+Description=Example "daemon"; with ${VARS}
+After=network.target
+After=remote-fs.target
+Wants=early.target
+Wants=
+
+[Service]
+Type=simple
+ExecStartPre=/bin/mkdir -p /run/example
+ExecStartPre=-/bin/rm -f /run/example/lock
+ExecStart=/usr/bin/example \
+    --config /etc/example.conf \
+    --verbose
+Environment="A=1" "B=2"
+Restart=on-failure
+RestartSec=5
+remainafterexit=yes
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	unit := UnitFile{}
+	errors := Parse(strings.NewReader(iniContent), &unit, WithDialect(DialectSystemd))
+	if errors != nil {
+		t.Fatalf("Failed to parse unit file: %v", errors)
+	}
+
+	if unit.Unit.Description != `Example "daemon"; with ${VARS}` {
+		t.Errorf("Expected raw description, got %q", unit.Unit.Description)
+	}
+	if !reflect.DeepEqual(unit.Unit.After, []string{"network.target", "remote-fs.target"}) {
+		t.Errorf("Unexpected After: %q", unit.Unit.After)
+	}
+	if unit.Unit.Wants != nil {
+		t.Errorf("Expected empty assignment to clear Wants, got %q", unit.Unit.Wants)
+	}
+	if !reflect.DeepEqual(unit.Service.ExecStartPre, []string{"/bin/mkdir -p /run/example", "-/bin/rm -f /run/example/lock"}) {
+		t.Errorf("Unexpected ExecStartPre: %q", unit.Service.ExecStartPre)
+	}
+	if unit.Service.ExecStart != "/usr/bin/example --config /etc/example.conf --verbose" {
+		t.Errorf("Unexpected ExecStart: %q", unit.Service.ExecStart)
+	}
+	if !reflect.DeepEqual(unit.Service.Environment, []string{`"A=1" "B=2"`}) {
+		t.Errorf("Unexpected Environment: %q", unit.Service.Environment)
+	}
+	if unit.Service.RestartSec != 5 || unit.Service.Restart != "on-failure" {
+		t.Errorf("Unexpected restart settings: %+v", unit.Service)
+	}
+	if unit.Service.RemainAfterOK == nil || !*unit.Service.RemainAfterOK {
+		t.Errorf("Expected remainafterexit to match the RemainAfterExit tag and be true, got %v", unit.Service.RemainAfterOK)
+	}
+	if !reflect.DeepEqual(unit.Install.WantedBy, []string{"multi-user.target"}) {
+		t.Errorf("Unexpected WantedBy: %q", unit.Install.WantedBy)
+	}
+}
+
+func TestWrite_SystemdDialect(t *testing.T) {
+	unit := &UnitFile{
+		Unit: UnitSection{
+			Description: "Example; daemon",
+			After:       []string{"network.target", "remote-fs.target"},
+		},
+		Service: ServiceSection{
+			Type:         "simple",
+			ExecStartPre: []string{"/bin/mkdir -p /run/example"},
+			ExecStart:    "/usr/bin/example --verbose",
+			RestartSec:   5,
+		},
+		Install: InstallSection{WantedBy: []string{"multi-user.target"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, unit, WithDialect(DialectSystemd)); err != nil {
+		t.Fatalf("Failed to write unit file: %v", err)
+	}
+
+	expected := `
+[Unit]
+Description=Example; daemon
+After=network.target
+After=remote-fs.target
+
+[Service]
+Type=simple
+ExecStartPre=/bin/mkdir -p /run/example
+ExecStart=/usr/bin/example --verbose
+Restart=
+RestartSec=5
+RemainAfterExit=
+
+[Install]
+WantedBy=multi-user.target
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWrite_SystemdDialectUnrepresentableValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  ServiceSection
+		expected string
+	}{
+		{
+			"trailing backslash",
+			ServiceSection{ExecStart: `C:\dir\`},
+			"cannot write value of 'ExecStart': a trailing backslash would continue it on the next line",
+		},
+		{
+			"line break",
+			ServiceSection{ExecStart: "/bin/true\nExecStop=/bin/false"},
+			"cannot write value of 'ExecStart': it contains a line break",
+		},
+		{
+			"line break in repeated key",
+			ServiceSection{ExecStartPre: []string{"a\rb"}},
+			"cannot write value of 'ExecStartPre': it contains a line break",
+		},
+	}
+
+	for _, test := range tests {
+		unit := &UnitFile{Service: test.service}
+		var buf bytes.Buffer
+		err := Write(&buf, unit, WithDialect(DialectSystemd))
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestParse_AppendSlicesTypeError(t *testing.T) {
+	type Ports struct {
+		Listen []int
+	}
+
+	config := Ports{}
+	errors := Parse(strings.NewReader("Listen=80\nListen=http\n"), &config, WithDialect(DialectSystemd))
+	if errors == nil || !strings.Contains(errors[0].Error(), "error at line 2: invalid value for field type int: http") {
+		t.Fatalf("Expected error for invalid slice element, got %v", errors)
+	}
+	if !reflect.DeepEqual(config.Listen, []int{80}) {
+		t.Errorf("Expected valid elements to be kept, got %v", config.Listen)
+	}
+}
+
+type DesktopEntry struct {
+	Type        string
+	Name        LocalizedString
+	GenericName map[string]string
+	Exec        string
+	Categories  []string
+	Keywords    []string
+	Terminal    bool
+}
+
+type DesktopFile struct {
+	Entry DesktopEntry `ini:"Desktop Entry"`
+}
+
+func TestParse_DesktopDialect(t *testing.T) {
+	iniContent := `
+# Example desktop entry
+[Desktop Entry]
+Type=Application
+Name=Files
+Name[de]=Dateien
+Name[sr@latin]=Datoteke
+GenericName=File Manager
+GenericName[fr]=Gestionnaire de fichiers
+Exec=example %U --title="a # b"
+Categories=GTK;System;Utility\;Tools;
+Terminal=false
+`
+
+	desktop := DesktopFile{}
+	errors := Parse(strings.NewReader(iniContent), &desktop, WithDialect(DialectDesktop))
+	if errors != nil {
+		t.Fatalf("Failed to parse desktop entry: %v", errors)
+	}
+
+	entry := desktop.Entry
+	expectedName := LocalizedString{Default: "Files", Locales: map[string]string{"de": "Dateien", "sr@latin": "Datoteke"}}
+	if !reflect.DeepEqual(entry.Name, expectedName) {
+		t.Errorf("Unexpected Name: %+v", entry.Name)
+	}
+	if !reflect.DeepEqual(entry.GenericName, map[string]string{"": "File Manager", "fr": "Gestionnaire de fichiers"}) {
+		t.Errorf("Unexpected GenericName: %q", entry.GenericName)
+	}
+	if entry.Exec != `example %U --title="a # b"` {
+		t.Errorf("Expected raw Exec, got %q", entry.Exec)
+	}
+	if !reflect.DeepEqual(entry.Categories, []string{"GTK", "System", "Utility;Tools"}) {
+		t.Errorf("Unexpected Categories: %q", entry.Categories)
+	}
+	if entry.Type != "Application" || entry.Terminal {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestParse_DesktopDialectSections(t *testing.T) {
+	type Untagged struct {
+		DesktopEntry DesktopEntry
+	}
+
+	config := Untagged{}
+	if errors := Parse(strings.NewReader("[Desktop Entry]\nName=Files\n"), &config, WithDialect(DialectDesktop)); errors != nil {
+		t.Fatalf("Expected [Desktop Entry] to match the DesktopEntry field, got %v", errors)
+	}
+	if config.DesktopEntry.Name.Default != "Files" {
+		t.Errorf("Unexpected Name: %+v", config.DesktopEntry.Name)
+	}
+
+	if errors := Parse(strings.NewReader("[Desktop [Entry]\n"), &config, WithDialect(DialectDesktop)); errors == nil {
+		t.Error("Expected error for a section name with a bracket")
+	}
+	if errors := Parse(strings.NewReader("[Desktop Entry]\n"), &config); errors == nil {
+		t.Error("Expected error for a section name with spaces outside the desktop dialect")
+	}
+}
+
+func TestWrite_DesktopDialect(t *testing.T) {
+	desktop := &DesktopFile{
+		Entry: DesktopEntry{
+			Type:        "Application",
+			Name:        LocalizedString{Default: "Files", Locales: map[string]string{"fr": "Fichiers", "de": "Dateien"}},
+			GenericName: map[string]string{"": "File Manager"},
+			Exec:        "example %U",
+			Categories:  []string{"GTK", "Utility;Tools"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, desktop, WithDialect(DialectDesktop)); err != nil {
+		t.Fatalf("Failed to write desktop entry: %v", err)
+	}
+
+	expected := `
+[Desktop Entry]
+Type=Application
+Name=Files
+Name[de]=Dateien
+Name[fr]=Fichiers
+GenericName=File Manager
+Exec=example %U
+Categories=GTK;Utility\;Tools;
+Keywords=
+Terminal=false
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	parsed := DesktopFile{}
+	if errors := Parse(strings.NewReader(buf.String()), &parsed, WithDialect(DialectDesktop)); errors != nil {
+		t.Fatalf("Failed to parse written desktop entry: %v", errors)
+	}
+	if !reflect.DeepEqual(parsed.Entry.Name, desktop.Entry.Name) || !reflect.DeepEqual(parsed.Entry.Categories, desktop.Entry.Categories) {
+		t.Errorf("Round trip mismatch: %+v", parsed.Entry)
+	}
+}
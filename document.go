@@ -0,0 +1,233 @@
+package simpleini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Document is an editable representation of an INI file. Unlike Parse, it keeps
+// the order of sections and keys along with their comments, so a file can be
+// modified and written back without losing its annotations.
+type Document struct {
+	// Sections holds the sections in file order. The first section is the root
+	// section, which has an empty name and no header.
+	Sections []*Section
+	// Trailer holds the comment and blank lines that follow the last key.
+	Trailer []string
+
+	delimiter string
+	caseMode  CaseMode
+}
+
+// Section is a named group of keys within a Document.
+type Section struct {
+	Name string
+	// Comments holds the comment and blank lines that precede the section header.
+	Comments []string
+	// Comment is the inline comment after the section header, including its prefix.
+	Comment string
+	Keys    []*Key
+
+	caseMode CaseMode
+}
+
+// Key is a single key-value pair within a Section.
+type Key struct {
+	Name string
+	// Value is the raw value. Multiline values are joined with newlines.
+	Value string
+	// Comments holds the comment and blank lines that precede the key.
+	Comments []string
+	// Comment is the inline comment after the value, including its prefix.
+	Comment string
+	// Line is the line number of the key in the source, or 0 for added keys.
+	Line int
+}
+
+// NewDocument returns an empty Document containing only the root section.
+func NewDocument(opts ...Option) *Document {
+	o := newOptions(opts)
+	return &Document{
+		Sections:  []*Section{{caseMode: o.caseMode}},
+		delimiter: o.delimiter,
+		caseMode:  o.caseMode,
+	}
+}
+
+// ParseDocument reads INI content into a Document. Include directives are kept
+// as-is and are not followed.
+func ParseDocument(reader io.Reader, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+	doc := NewDocument(opts...)
+	section := doc.Sections[0]
+	var lastKey *Key
+	var pending []string
+	var errs []error
+
+	scanner := newLineScanner(reader, o.limits, o.encoding)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if err := o.limits.checkLine(scanner.Text(), lineNumber); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		raw, err := ensureValidUTF8(scanner.Text())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error at line %d: %w", lineNumber, err))
+			continue
+		}
+		line := strings.TrimSpace(raw)
+
+		// Check for multiline continuation
+		if line != "" && lastKey != nil && len(pending) == 0 && (raw[0] == ' ' || raw[0] == '\t') {
+			value, comment := splitInlineComment(line, o.inlineComments)
+			lastKey.Value += "\n" + value
+			if lastKey.Comment == "" {
+				lastKey.Comment = comment
+			}
+			continue
+		}
+
+		// Keep comments, blank lines and directives for the next element
+		if line == "" || line[0] == ';' || line[0] == '#' || line[0] == '!' {
+			pending = append(pending, strings.TrimRight(raw, " \t"))
+			continue
+		}
+
+		line, comment := splitInlineComment(line, o.inlineComments)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := line[1 : len(line)-1]
+			if o.caseMode == CaseInsensitive {
+				name = strings.ToLower(name)
+			}
+			if !isValidSection(name) {
+				errs = append(errs, fmt.Errorf("invalid section name at line %d: %s", lineNumber, name))
+				continue
+			}
+			section = &Section{Name: name, Comments: pending, Comment: comment, caseMode: o.caseMode}
+			doc.Sections = append(doc.Sections, section)
+			lastKey, pending = nil, nil
+			continue
+		}
+
+		keyValue := strings.SplitN(line, o.delimiter, 2)
+		if len(keyValue) != 2 {
+			errs = append(errs, fmt.Errorf("invalid line format at line %d: %s", lineNumber, line))
+			continue
+		}
+		name := strings.TrimSpace(keyValue[0])
+		if o.caseMode == CaseInsensitive {
+			name = strings.ToLower(name)
+		}
+		if _, _, dotted := cutDottedKey(section.Name, name); !isValidKey(name) && !dotted {
+			errs = append(errs, fmt.Errorf("invalid key name at line %d: %s", lineNumber, name))
+			continue
+		}
+		lastKey = &Key{
+			Name:     name,
+			Value:    strings.TrimSpace(keyValue[1]),
+			Comments: pending,
+			Comment:  comment,
+			Line:     lineNumber,
+		}
+		section.Keys = append(section.Keys, lastKey)
+		pending = nil
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, o.limits.scanError(err, lineNumber))
+	}
+	doc.Trailer = pending
+
+	if len(errs) > 0 {
+		return doc, errors.Join(errs...)
+	}
+	return doc, nil
+}
+
+// Section returns the section with the given name, or nil if there is none.
+// The root section has an empty name. Names are matched ignoring case, unless
+// the document was created with CaseSensitive.
+func (d *Document) Section(name string) *Section {
+	for _, s := range d.Sections {
+		if namesEqual(d.caseMode, s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddSection returns the section with the given name, appending it to the
+// document if it does not exist yet.
+func (d *Document) AddSection(name string) *Section {
+	if s := d.Section(name); s != nil {
+		return s
+	}
+	s := &Section{Name: name, caseMode: d.caseMode}
+	d.Sections = append(d.Sections, s)
+	return s
+}
+
+// Key returns the key with the given name, or nil if there is none. Names are
+// matched like section names.
+func (s *Section) Key(name string) *Key {
+	for _, k := range s.Keys {
+		if namesEqual(s.caseMode, k.Name, name) {
+			return k
+		}
+	}
+	return nil
+}
+
+// SetValue sets the value of the key with the given name, appending the key
+// to the section if it does not exist yet. Existing comments are kept.
+func (s *Section) SetValue(name, value string) *Key {
+	k := s.Key(name)
+	if k == nil {
+		k = &Key{Name: name}
+		s.Keys = append(s.Keys, k)
+	}
+	k.Value = value
+	return k
+}
+
+// WriteTo writes the document to w in INI format, implementing io.WriterTo.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, s := range d.Sections {
+		writeLines(&buf, s.Comments)
+		if s.Name != "" {
+			buf.WriteString("[" + s.Name + "]")
+			writeInlineComment(&buf, s.Comment)
+		}
+		for _, k := range s.Keys {
+			writeLines(&buf, k.Comments)
+			lines := strings.Split(k.Value, "\n")
+			fmt.Fprintf(&buf, "%s %s %s", k.Name, d.delimiter, lines[0])
+			writeInlineComment(&buf, k.Comment)
+			for _, line := range lines[1:] {
+				buf.WriteString("\t" + line + "\n")
+			}
+		}
+	}
+	writeLines(&buf, d.Trailer)
+	return buf.WriteTo(w)
+}
+
+// writeLines writes each line followed by a newline.
+func writeLines(buf *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		buf.WriteString(line + "\n")
+	}
+}
+
+// writeInlineComment terminates the current line, preceded by the comment if there is one.
+func writeInlineComment(buf *bytes.Buffer, comment string) {
+	if comment != "" {
+		buf.WriteString(" " + comment)
+	}
+	buf.WriteString("\n")
+}
@@ -0,0 +1,137 @@
+package simpleini
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseDocument_RoundTrip(t *testing.T) {
+	iniContent := `; Application settings
+app_name = MyApp ; display name
+
+# Server settings
+[server] ; primary
+host = localhost
+port = 8080 ; http port
+servers = one
+	two
+
+!include other.ini
+; trailing comment
+`
+
+	doc, err := ParseDocument(strings.NewReader(iniContent), WithInlineComments())
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	if buf.String() != iniContent {
+		t.Errorf("Round trip mismatch:\nexpected:\n%s\ngot:\n%s", iniContent, buf.String())
+	}
+}
+
+func TestParseDocument_Lookup(t *testing.T) {
+	iniContent := `
+app_name = MyApp
+
+[Server]
+Host = localhost ; main host
+port = 8080
+    8081
+`
+
+	doc, err := ParseDocument(strings.NewReader(iniContent), WithInlineComments())
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	if len(doc.Sections) != 2 {
+		t.Fatalf("Expected 2 sections, got %d", len(doc.Sections))
+	}
+	if k := doc.Section("").Key("app_name"); k == nil || k.Value != "MyApp" || k.Line != 2 {
+		t.Errorf("Unexpected root key: %+v", k)
+	}
+	server := doc.Section("server")
+	if server == nil {
+		t.Fatal("Expected server section")
+	}
+	if k := server.Key("host"); k == nil || k.Value != "localhost" || k.Comment != "; main host" {
+		t.Errorf("Unexpected host key: %+v", k)
+	}
+	if k := server.Key("port"); k == nil || k.Value != "8080\n8081" {
+		t.Errorf("Unexpected multiline port key: %+v", k)
+	}
+	if doc.Section("missing") != nil {
+		t.Error("Expected nil for missing section")
+	}
+}
+
+func TestDocument_Edit(t *testing.T) {
+	iniContent := `[server]
+port = 8080 ; http port
+`
+
+	doc, err := ParseDocument(strings.NewReader(iniContent), WithInlineComments())
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	server := doc.Section("server")
+	server.SetValue("port", "9090")
+	server.SetValue("host", "localhost")
+	doc.AddSection("database").SetValue("name", "app")
+
+	expected := `[server]
+port = 9090 ; http port
+host = localhost
+[database]
+name = app
+`
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestNewDocument_CustomDelimiter(t *testing.T) {
+	doc := NewDocument(WithDelimiter(":"))
+	doc.Section("").SetValue("app_name", "MyApp")
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	if buf.String() != "app_name : MyApp\n" {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+}
+
+func TestParseDocument_Errors(t *testing.T) {
+	iniContent := `
+[invalid-section]
+invalid line
+bad-key = value
+`
+
+	_, err := ParseDocument(strings.NewReader(iniContent))
+	if err == nil {
+		t.Fatal("Expected errors, got nil")
+	}
+	for _, expected := range []string{
+		"invalid section name at line 2",
+		"invalid line format at line 3",
+		"invalid key name at line 4",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error %q in %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package simpleini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type DottedLogging struct {
+	Level  string
+	Output string
+}
+
+type DottedServer struct {
+	Port    int
+	Logging DottedLogging
+}
+
+type DottedConfig struct {
+	Name    string
+	Server  DottedServer
+	Workers map[string]struct{ Queue string }
+	Ports   []int
+}
+
+func TestParse_DottedKeys(t *testing.T) {
+	iniContent := `
+name = app
+server.port = 80
+server.logging.level = debug
+workers.a.queue = fast
+ports = 1
+
+[server]
+logging.output = stderr
+
+[server.logging]
+level = info
+
+[workers]
+b.queue = slow
+`
+
+	config := DottedConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with dotted keys: %v", errors)
+	}
+
+	expected := DottedConfig{
+		Name:    "app",
+		Server:  DottedServer{Port: 80, Logging: DottedLogging{Level: "info", Output: "stderr"}},
+		Workers: map[string]struct{ Queue string }{"a": {Queue: "fast"}, "b": {Queue: "slow"}},
+		Ports:   []int{1},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestParse_DottedKeyReferences(t *testing.T) {
+	iniContent := `
+server.logging.level = debug
+name = ${server.logging.level}
+`
+
+	config := DottedConfig{}
+	if errors := Parse(strings.NewReader(iniContent), &config); errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if config.Name != "debug" {
+		t.Errorf("Expected a reference to a dotted key, got %q", config.Name)
+	}
+}
+
+func TestParse_DottedKeyDuplicates(t *testing.T) {
+	iniContent := "server.port = 80\n[server]\nport = 81\n"
+
+	config := DottedConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithDuplicateKeys(DuplicateError))
+	if len(errors) != 1 || errors[0].Error() != "duplicate key 'port' at line 3, first set at line 1" {
+		t.Errorf("Expected a dotted key and a section key to be the same key, got %v", errors)
+	}
+}
+
+func TestParse_InvalidDottedKeys(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"server..port = 80\n", "invalid key name at line 1: server..port"},
+		{".port = 80\n", "invalid key name at line 1: .port"},
+		{"server. = 80\n", "invalid key name at line 1: server."},
+		{"server.missing.level = x\n", "error at line 1: no matching field found for section 'server.missing'"},
+	}
+
+	for _, test := range tests {
+		config := DottedConfig{}
+		errors := Parse(strings.NewReader(test.content), &config)
+		if len(errors) != 1 || errors[0].Error() != test.expected {
+			t.Errorf("%q: expected error %q, got %v", test.content, test.expected, errors)
+		}
+	}
+}
+
+func TestCutDottedKey(t *testing.T) {
+	tests := []struct {
+		header  string
+		key     string
+		section string
+		name    string
+		ok      bool
+	}{
+		{"", "server.port", "server", "port", true},
+		{"", "server.logging.level", "server.logging", "level", true},
+		{"server", "logging.level", "server.logging", "level", true},
+		{`remote "origin"`, "a.b", `remote "origin"`, "a.b", false},
+		{"", "a..b", "", "a..b", false},
+	}
+
+	for _, test := range tests {
+		section, name, ok := cutDottedKey(test.header, test.key)
+		if section != test.section || name != test.name || ok != test.ok {
+			t.Errorf("cutDottedKey(%q, %q) = (%q, %q, %v); expected (%q, %q, %v)", test.header, test.key, section, name, ok, test.section, test.name, test.ok)
+		}
+	}
+}
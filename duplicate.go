@@ -0,0 +1,152 @@
+package simpleini
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DuplicatePolicy controls what happens when a key or section is repeated in
+// the same file. Keys set again by an included file or a profile are not
+// duplicates.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLastWins sets a repeated key again, so the last value wins. A
+	// repeated section is merged into the first. This is the default.
+	DuplicateLastWins DuplicatePolicy = iota
+	// DuplicateFirstWins ignores a repeated key, or the keys of a repeated
+	// section.
+	DuplicateFirstWins
+	// DuplicateError reports an error with both lines, and ignores the
+	// repeated key or section.
+	DuplicateError
+	// DuplicateWarn passes a warning with both lines to the warning handler,
+	// and otherwise behaves like DuplicateLastWins.
+	DuplicateWarn
+	// DuplicateAppend appends the value of a repeated key to its slice field,
+	// and reports an error for other fields. A repeated section is merged.
+	DuplicateAppend
+)
+
+// WithDuplicateKeys sets the policy for keys repeated in a section.
+func WithDuplicateKeys(policy DuplicatePolicy) Option {
+	return func(o *options) {
+		o.duplicateKeys = policy
+	}
+}
+
+// WithDuplicateSections sets the policy for repeated section headers.
+func WithDuplicateSections(policy DuplicatePolicy) Option {
+	return func(o *options) {
+		o.duplicateSections = policy
+	}
+}
+
+// seenName identifies a section, or a key within it, in a single file.
+type seenName struct {
+	profile string
+	section string
+	key     string
+}
+
+// checkDuplicateSection applies the duplicate section policy to the header of
+// the current section. It reports true if the keys of the section are ignored.
+func (d *decoder) checkDuplicateSection(st *lineState, lineNumber int) (bool, error) {
+	if d.opts.duplicateSections == DuplicateLastWins {
+		return false, nil
+	}
+	name := seenName{profile: st.profile, section: d.foldName(st.section)}
+	first, repeated := st.sectionLines[name]
+	if !repeated {
+		if st.sectionLines == nil {
+			st.sectionLines = make(map[seenName]int)
+		}
+		st.sectionLines[name] = lineNumber
+		return false, nil
+	}
+
+	switch d.opts.duplicateSections {
+	case DuplicateFirstWins:
+		return true, nil
+	case DuplicateError:
+		return true, fmt.Errorf("duplicate section '%s' at line %d, first declared at line %d", st.section, lineNumber, first)
+	case DuplicateWarn:
+		d.warn(Warning{
+			Line:    lineNumber,
+			Section: st.section,
+			Message: fmt.Sprintf("duplicate section '%s', first declared at line %d", st.section, first),
+		})
+	}
+	return false, nil
+}
+
+// checkDuplicateKey applies the duplicate key policy to the current key. It
+// reports true if the value is ignored, and whether it is appended to the
+// field. Array keys such as key[] are meant to be repeated and are not checked,
+// while keys naming the same field through an alias are.
+func (d *decoder) checkDuplicateKey(st *lineState, lineNumber int) (skip, appended bool, err error) {
+	if st.indexed || d.opts.duplicateKeys == DuplicateLastWins {
+		return false, false, nil
+	}
+	// An alias repeats the key of its field
+	key := st.key
+	if field, ok := d.lookupField(st.section, key); ok {
+		key = d.fieldName(field)
+	}
+	name := seenName{profile: st.profile, section: d.foldName(st.section), key: d.foldName(key)}
+	first, repeated := st.keyLines[name]
+	if !repeated {
+		if st.keyLines == nil {
+			st.keyLines = make(map[seenName]int)
+		}
+		st.keyLines[name] = lineNumber
+		return false, false, nil
+	}
+
+	switch d.opts.duplicateKeys {
+	case DuplicateFirstWins:
+		return true, false, nil
+	case DuplicateWarn:
+		d.warn(Warning{
+			Line:    lineNumber,
+			Section: st.section,
+			Key:     st.key,
+			Message: fmt.Sprintf("duplicate key '%s', first set at line %d", st.key, first),
+		})
+	case DuplicateAppend:
+		if field, ok := d.lookupField(st.section, st.key); ok && isAppendable(field.Type) {
+			return false, true, nil
+		}
+		fallthrough
+	case DuplicateError:
+		return true, false, fmt.Errorf("duplicate key '%s' at line %d, first set at line %d", st.key, lineNumber, first)
+	}
+	return false, false, nil
+}
+
+// appendConfigValue appends the value of a repeated key to its slice field. A
+// list or multiline value appends all of its elements.
+func (d *decoder) appendConfigValue(section, key, value string) error {
+	return d.withSection(section, func(v reflect.Value) error {
+		field, err := d.findField(v, key)
+		if err != nil {
+			return err
+		}
+		fieldValue := v.FieldByName(field.Name)
+		elements := reflect.New(indirectType(field.Type)).Elem()
+		if sep := fieldSeparator(field, d.opts.sliceSeparator); sep != "" {
+			err = setListValue(elements, value, sep)
+		} else {
+			err = setFieldValue(elements, value)
+		}
+		if err != nil {
+			return err
+		}
+		fieldValue = initializePointer(fieldValue, true)
+		if err := d.opts.limits.checkSliceLength(fieldValue.Len()+elements.Len(), key); err != nil {
+			return err
+		}
+		fieldValue.Set(reflect.AppendSlice(fieldValue, elements))
+		return nil
+	})
+}
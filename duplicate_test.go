@@ -0,0 +1,218 @@
+package simpleini
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type DuplicateServer struct {
+	Host  string
+	Ports []int `sep:","`
+	Tags  []string
+}
+
+type DuplicateConfig struct {
+	Name   string
+	Server DuplicateServer
+}
+
+const duplicateKeysContent = `
+name = first
+[server]
+host = a
+ports = 80
+tags = x
+host = b
+ports = 443, 8443
+tags = y
+`
+
+func TestParse_DuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   DuplicatePolicy
+		expected DuplicateServer
+		errors   []string
+	}{
+		{"last wins", DuplicateLastWins, DuplicateServer{Host: "b", Ports: []int{443, 8443}, Tags: []string{"y"}}, nil},
+		{"first wins", DuplicateFirstWins, DuplicateServer{Host: "a", Ports: []int{80}, Tags: []string{"x"}}, nil},
+		{"warn", DuplicateWarn, DuplicateServer{Host: "b", Ports: []int{443, 8443}, Tags: []string{"y"}}, nil},
+		{
+			"error", DuplicateError, DuplicateServer{Host: "a", Ports: []int{80}, Tags: []string{"x"}},
+			[]string{
+				"duplicate key 'host' at line 7, first set at line 4",
+				"duplicate key 'ports' at line 8, first set at line 5",
+				"duplicate key 'tags' at line 9, first set at line 6",
+			},
+		},
+		{
+			"append", DuplicateAppend, DuplicateServer{Host: "a", Ports: []int{80, 443, 8443}, Tags: []string{"x", "y"}},
+			[]string{"duplicate key 'host' at line 7, first set at line 4"},
+		},
+	}
+
+	for _, test := range tests {
+		config := DuplicateConfig{}
+		errors := Parse(strings.NewReader(duplicateKeysContent), &config, WithDuplicateKeys(test.policy))
+		if len(errors) != len(test.errors) {
+			t.Errorf("%s: expected %d errors, got %v", test.name, len(test.errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.errors[i] {
+				t.Errorf("%s: expected error %q, got %q", test.name, test.errors[i], err.Error())
+			}
+		}
+		if !reflect.DeepEqual(config.Server, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, config.Server)
+		}
+	}
+}
+
+func TestParse_DuplicateSections(t *testing.T) {
+	iniContent := `
+[server]
+host = a
+tags = x
+
+[server]
+host = b
+`
+
+	tests := []struct {
+		name     string
+		policy   DuplicatePolicy
+		expected DuplicateServer
+		errors   []string
+	}{
+		{
+			// The merged section repeats the host key
+			"merge", DuplicateLastWins, DuplicateServer{Host: "a", Tags: []string{"x"}},
+			[]string{"duplicate key 'host' at line 7, first set at line 3"},
+		},
+		{"first wins", DuplicateFirstWins, DuplicateServer{Host: "a", Tags: []string{"x"}}, nil},
+		{
+			"error", DuplicateError, DuplicateServer{Host: "a", Tags: []string{"x"}},
+			[]string{"duplicate section 'server' at line 6, first declared at line 2"},
+		},
+	}
+
+	for _, test := range tests {
+		config := DuplicateConfig{}
+		errors := Parse(strings.NewReader(iniContent), &config, WithDuplicateSections(test.policy), WithDuplicateKeys(DuplicateError))
+		if len(errors) != len(test.errors) {
+			t.Errorf("%s: expected %d errors, got %v", test.name, len(test.errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.errors[i] {
+				t.Errorf("%s: expected error %q, got %q", test.name, test.errors[i], err.Error())
+			}
+		}
+		if !reflect.DeepEqual(config.Server, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, config.Server)
+		}
+	}
+}
+
+func TestParse_DuplicateWarnings(t *testing.T) {
+	iniContent := "[server]\nhost = a\n[SERVER]\nHost = b\n"
+
+	var warnings []string
+	config := DuplicateConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config,
+		WithDuplicateKeys(DuplicateWarn),
+		WithDuplicateSections(DuplicateWarn),
+		WithWarningHandler(func(w Warning) { warnings = append(warnings, w.String()) }),
+	)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	expected := []string{
+		"warning at line 3: duplicate section 'server', first declared at line 1",
+		"warning at line 4: duplicate key 'host', first set at line 2",
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected warnings %q, got %q", expected, warnings)
+	}
+	if config.Server.Host != "b" {
+		t.Errorf("Expected the last value to win, got %+v", config.Server)
+	}
+}
+
+func TestParse_DuplicateExemptions(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":  "name = main\n!include local.ini\n[server]\ntags[] = a\ntags[] = b\n[server@prod]\nhost = prod\n[server]\nhost = main\n",
+		"local.ini": "name = local\n",
+	})
+
+	config := DuplicateConfig{}
+	errors := ParseFile(filepath.Join(dir, "main.ini"), &config,
+		WithDuplicateKeys(DuplicateError),
+		WithArrayKeys(),
+		WithProfile("prod"),
+	)
+	if errors != nil {
+		t.Fatalf("Expected includes, array keys and profiles not to be duplicates, got %v", errors)
+	}
+	expected := DuplicateConfig{Name: "local", Server: DuplicateServer{Host: "prod", Tags: []string{"a", "b"}}}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestParse_DuplicateMultilineKeys(t *testing.T) {
+	type Config struct {
+		Hosts []string
+		Name  string
+	}
+
+	tests := []struct {
+		name     string
+		policy   DuplicatePolicy
+		content  string
+		expected Config
+		errors   []string
+	}{
+		{"error", DuplicateError, "hosts = a\n  b\n  c\nname = x\n", Config{Hosts: []string{"a", "b", "c"}, Name: "x"}, nil},
+		{"warn", DuplicateWarn, "hosts = a\n  b\n  c\nname = x\n", Config{Hosts: []string{"a", "b", "c"}, Name: "x"}, nil},
+		{"first wins", DuplicateFirstWins, "hosts = a\n  b\n  c\nname = x\n", Config{Hosts: []string{"a", "b", "c"}, Name: "x"}, nil},
+		{"append", DuplicateAppend, "hosts = a\n  b\n  c\nname = x\n", Config{Hosts: []string{"a", "b", "c"}, Name: "x"}, nil},
+		{
+			"append repeated", DuplicateAppend, "hosts = a\n  b\nname = x\nhosts = c\n  d\n",
+			Config{Hosts: []string{"a", "b", "c", "d"}, Name: "x"}, nil,
+		},
+		{
+			"error repeated", DuplicateError, "hosts = a\n  b\nhosts = c\n  d\nname = x\n",
+			Config{Hosts: []string{"a", "b"}, Name: "x"},
+			[]string{"duplicate key 'hosts' at line 3, first set at line 1"},
+		},
+	}
+
+	for _, test := range tests {
+		var warnings []Warning
+		config := Config{}
+		errors := Parse(strings.NewReader(test.content), &config,
+			WithDuplicateKeys(test.policy),
+			WithWarningHandler(func(w Warning) { warnings = append(warnings, w) }),
+		)
+		if len(errors) != len(test.errors) {
+			t.Errorf("%s: expected %d errors, got %v", test.name, len(test.errors), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.errors[i] {
+				t.Errorf("%s: expected error %q, got %q", test.name, test.errors[i], err.Error())
+			}
+		}
+		if warnings != nil {
+			t.Errorf("%s: expected no warnings, got %v", test.name, warnings)
+		}
+		if !reflect.DeepEqual(config, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, config)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package simpleini
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of the input, which is transcoded to
+// UTF-8 before parsing.
+type Encoding int
+
+const (
+	// EncodingUTF8 reads UTF-8, the default.
+	EncodingUTF8 Encoding = iota
+	// EncodingUTF16LE reads little-endian UTF-16.
+	EncodingUTF16LE
+	// EncodingUTF16BE reads big-endian UTF-16.
+	EncodingUTF16BE
+	// EncodingLatin1 reads ISO 8859-1.
+	EncodingLatin1
+	// EncodingWindows1252 reads the Windows-1252 code page, a superset of
+	// ISO 8859-1 with printable characters in place of most C1 controls.
+	EncodingWindows1252
+)
+
+// Byte order marks
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252. The five undefined
+// bytes map to the C1 controls, as in ISO 8859-1.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// errTruncatedUTF16 is returned for UTF-16 input with an odd number of bytes.
+var errTruncatedUTF16 = errors.New("truncated UTF-16 input")
+
+// WithEncoding sets the encoding of the input. A byte order mark at the start
+// of the input takes precedence, so UTF-8 and UTF-16 files with a byte order
+// mark are read correctly with any encoding.
+func WithEncoding(encoding Encoding) Option {
+	return func(o *options) {
+		o.encoding = encoding
+	}
+}
+
+// decodeInput returns a reader of the input transcoded to UTF-8, without a
+// byte order mark.
+func decodeInput(reader io.Reader, encoding Encoding) io.Reader {
+	br := bufio.NewReader(reader)
+	bom, _ := br.Peek(len(utf8BOM))
+	switch {
+	case bytes.HasPrefix(bom, utf8BOM):
+		br.Discard(len(utf8BOM))
+		encoding = EncodingUTF8
+	case bytes.HasPrefix(bom, utf16LEBOM):
+		br.Discard(len(utf16LEBOM))
+		encoding = EncodingUTF16LE
+	case bytes.HasPrefix(bom, utf16BEBOM):
+		br.Discard(len(utf16BEBOM))
+		encoding = EncodingUTF16BE
+	}
+
+	switch encoding {
+	case EncodingUTF16LE:
+		return &runeReader{r: br, next: func(r *bufio.Reader) (rune, error) { return readUTF16(r, false) }}
+	case EncodingUTF16BE:
+		return &runeReader{r: br, next: func(r *bufio.Reader) (rune, error) { return readUTF16(r, true) }}
+	case EncodingLatin1:
+		return &runeReader{r: br, next: readLatin1}
+	case EncodingWindows1252:
+		return &runeReader{r: br, next: readWindows1252}
+	default:
+		return br
+	}
+}
+
+// runeReader encodes the runes read by next as UTF-8.
+type runeReader struct {
+	r    *bufio.Reader
+	next func(*bufio.Reader) (rune, error)
+}
+
+func (rr *runeReader) Read(p []byte) (int, error) {
+	n := 0
+	for n+utf8.UTFMax <= len(p) {
+		r, err := rr.next(rr.r)
+		if err != nil {
+			return n, err
+		}
+		n += utf8.EncodeRune(p[n:], r)
+	}
+	return n, nil
+}
+
+// readLatin1 reads a character of ISO 8859-1, whose bytes are the first 256
+// code points.
+func readLatin1(r *bufio.Reader) (rune, error) {
+	b, err := r.ReadByte()
+	return rune(b), err
+}
+
+// readWindows1252 reads a character of Windows-1252.
+func readWindows1252(r *bufio.Reader) (rune, error) {
+	b, err := r.ReadByte()
+	if b >= 0x80 && b < 0xA0 {
+		return windows1252[b-0x80], err
+	}
+	return rune(b), err
+}
+
+// readUTF16 reads a character of UTF-16, combining surrogate pairs. Unpaired
+// surrogates are replaced with U+FFFD.
+func readUTF16(r *bufio.Reader, bigEndian bool) (rune, error) {
+	unit, err := readUTF16Unit(r, bigEndian)
+	if err != nil || !utf16.IsSurrogate(rune(unit)) {
+		return rune(unit), err
+	}
+	if next, err := r.Peek(2); err == nil {
+		low := uint16(next[0]) | uint16(next[1])<<8
+		if bigEndian {
+			low = uint16(next[0])<<8 | uint16(next[1])
+		}
+		if decoded := utf16.DecodeRune(rune(unit), rune(low)); decoded != utf8.RuneError {
+			r.Discard(2)
+			return decoded, nil
+		}
+	}
+	return utf8.RuneError, nil
+}
+
+// readUTF16Unit reads a 16-bit code unit.
+func readUTF16Unit(r *bufio.Reader, bigEndian bool) (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errTruncatedUTF16
+		}
+		return 0, err
+	}
+	if bigEndian {
+		return uint16(b[0])<<8 | uint16(b[1]), nil
+	}
+	return uint16(b[0]) | uint16(b[1])<<8, nil
+}
+
+// scanLines is a bufio.SplitFunc for lines ending in \n, \r\n or a lone \r,
+// which is returned without the line ending.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		switch {
+		case data[i] == '\n':
+			return i + 1, data[:i], nil
+		case i+1 < len(data) && data[i+1] == '\n':
+			return i + 2, data[:i], nil
+		case i+1 < len(data) || atEOF:
+			return i + 1, data[:i], nil
+		default:
+			// Read more to see if a \n follows the \r
+			return 0, nil, nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,122 @@
+package simpleini
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+type EncodingConfig struct {
+	Name    string
+	Greet   string
+	Section struct {
+		Value string
+	}
+}
+
+// encodeUTF16 encodes the string as UTF-16 with a byte order mark.
+func encodeUTF16(s string, bigEndian bool) string {
+	units := append([]uint16{0xFEFF}, utf16.Encode([]rune(s))...)
+	var b strings.Builder
+	for _, unit := range units {
+		if bigEndian {
+			b.WriteByte(byte(unit >> 8))
+			b.WriteByte(byte(unit))
+		} else {
+			b.WriteByte(byte(unit))
+			b.WriteByte(byte(unit >> 8))
+		}
+	}
+	return b.String()
+}
+
+func TestParse_Encodings(t *testing.T) {
+	expected := EncodingConfig{Name: "café", Greet: "hi 👋"}
+	expected.Section.Value = "x"
+	content := "name = café\r\ngreet = hi 👋\r\n[section]\r\nvalue = x\r\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		encoding Encoding
+	}{
+		{"UTF-8 with BOM", "\xEF\xBB\xBF" + content, EncodingUTF8},
+		{"lone CR", strings.ReplaceAll(content, "\r\n", "\r"), EncodingUTF8},
+		{"UTF-16LE with BOM", encodeUTF16(content, false), EncodingUTF8},
+		{"UTF-16BE with BOM", encodeUTF16(content, true), EncodingUTF8},
+		{"BOM overrides the encoding", "\xEF\xBB\xBF" + content, EncodingLatin1},
+	}
+
+	for _, test := range tests {
+		config := EncodingConfig{}
+		errors := Parse(strings.NewReader(test.content), &config, WithEncoding(test.encoding))
+		if errors != nil {
+			t.Errorf("%s: failed to parse INI: %v", test.name, errors)
+			continue
+		}
+		if !reflect.DeepEqual(config, expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, expected, config)
+		}
+	}
+}
+
+func TestParse_LegacyEncodings(t *testing.T) {
+	content := "name = caf\xE9\ngreet = \x93hi\x94 \x80\n"
+
+	config := EncodingConfig{}
+	if errors := Parse(strings.NewReader(content), &config, WithEncoding(EncodingWindows1252)); errors != nil {
+		t.Fatalf("Failed to parse Windows-1252 INI: %v", errors)
+	}
+	if config.Name != "café" || config.Greet != "“hi” €" {
+		t.Errorf("Expected Windows-1252 to be transcoded, got %+v", config)
+	}
+
+	config = EncodingConfig{}
+	if errors := Parse(strings.NewReader(content), &config, WithEncoding(EncodingLatin1)); errors != nil {
+		t.Fatalf("Failed to parse Latin-1 INI: %v", errors)
+	}
+	if config.Name != "café" || config.Greet != "\u0093hi\u0094 \u0080" {
+		t.Errorf("Expected Latin-1 to be transcoded, got %+v", config)
+	}
+
+	config = EncodingConfig{}
+	errors := Parse(strings.NewReader(content), &config)
+	if errors == nil || !strings.Contains(errors[0].Error(), "invalid UTF-8 encoding") {
+		t.Errorf("Expected legacy input to be invalid UTF-8 by default, got %v", errors)
+	}
+}
+
+func TestParse_TruncatedUTF16(t *testing.T) {
+	content := encodeUTF16("name = x\n", false) + "\x00"
+
+	config := EncodingConfig{}
+	errors := Parse(strings.NewReader(content), &config)
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "truncated UTF-16 input") {
+		t.Errorf("Expected a truncated input error, got %v", errors)
+	}
+}
+
+func TestParseDocument_BOM(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader("\xEF\xBB\xBFname = x\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	if key := doc.Sections[0].Keys[0]; key.Name != "name" || key.Value != "x" {
+		t.Errorf("Expected the BOM and CRLF to be removed, got %+v", key)
+	}
+}
+
+func TestScanLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("a\nb\r\nc\rd\r\r\ne"))
+	scanner.Split(scanLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	expected := []string{"a", "b", "c", "d", "", "e"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+}
@@ -0,0 +1,109 @@
+package simpleini
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix starts an encrypted value, followed by the base64-encoded
+// ciphertext, as in password = enc:v1:BASE64.
+const encryptedPrefix = "enc:v1:"
+
+// Decrypter decrypts the ciphertext of enc:v1: values.
+type Decrypter interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// Encrypter encrypts the values of secret fields for Write.
+type Encrypter interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+}
+
+// WithDecrypter decrypts values of the form enc:v1:BASE64 with the decrypter.
+// Without a decrypter, such values are an error.
+func WithDecrypter(decrypter Decrypter) Option {
+	return func(o *options) {
+		o.decrypter = decrypter
+	}
+}
+
+// WithEncrypter makes Write encrypt the values of secret fields with the
+// encrypter, writing them as enc:v1:BASE64 instead of redacting them.
+func WithEncrypter(encrypter Encrypter) Option {
+	return func(o *options) {
+		o.encrypter = encrypter
+	}
+}
+
+// EncryptValue returns the plaintext encrypted as an enc:v1: value, for example
+// to prepare a value for an INI file.
+func EncryptValue(encrypter Encrypter, plaintext string) (string, error) {
+	ciphertext, err := encrypter.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptValue decrypts an enc:v1: value. Other values are returned unchanged.
+func decryptValue(decrypter Decrypter, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+	if decrypter == nil {
+		return "", errors.New("encrypted value requires a decrypter")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	plaintext, err := decrypter.Decrypt(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// AESGCM encrypts and decrypts values with AES in Galois/Counter Mode. The
+// ciphertext is the random nonce followed by the sealed plaintext.
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM returns an AESGCM for the key, which must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256.
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{aead: aead}, nil
+}
+
+// Encrypt seals the plaintext with a random nonce.
+func (a *AESGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt.
+func (a *AESGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := a.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errors.New("ciphertext too short")
+	}
+	return a.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+}
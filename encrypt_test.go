@@ -0,0 +1,168 @@
+package simpleini
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestAESGCM(t *testing.T) {
+	aesgcm, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create AESGCM: %v", err)
+	}
+
+	ciphertext, err := aesgcm.Encrypt([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	plaintext, err := aesgcm.Decrypt(ciphertext)
+	if err != nil || string(plaintext) != "hunter2" {
+		t.Errorf("Decrypt = %q, %v; expected the plaintext", plaintext, err)
+	}
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := aesgcm.Decrypt(ciphertext); err == nil {
+		t.Error("Expected error for a tampered ciphertext")
+	}
+	if _, err := aesgcm.Decrypt([]byte("short")); err == nil {
+		t.Error("Expected error for a short ciphertext")
+	}
+	if _, err := NewAESGCM([]byte("bad key")); err == nil {
+		t.Error("Expected error for an invalid key size")
+	}
+}
+
+func TestParse_EncryptedValues(t *testing.T) {
+	aesgcm, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create AESGCM: %v", err)
+	}
+	password, err := EncryptValue(aesgcm, "hunter2")
+	if err != nil {
+		t.Fatalf("Failed to encrypt value: %v", err)
+	}
+	if !strings.HasPrefix(password, "enc:v1:") {
+		t.Fatalf("Expected an enc:v1: value, got %q", password)
+	}
+
+	iniContent := "[database]\nhost = db.local\npassword = " + password + "\n"
+
+	config := SecretConfig{}
+	if errs := Parse(strings.NewReader(iniContent), &config, WithDecrypter(aesgcm)); errs != nil {
+		t.Fatalf("Failed to parse encrypted values: %v", errs)
+	}
+	if config.Database.Password != "hunter2" || config.Database.Host != "db.local" {
+		t.Errorf("Unexpected database config: %+v", config.Database)
+	}
+
+	config = SecretConfig{}
+	errs := Parse(strings.NewReader(iniContent), &config)
+	if len(errs) != 1 || errs[0].Error() != "error at line 3: encrypted value requires a decrypter" {
+		t.Errorf("Expected error without a decrypter, got %v", errs)
+	}
+}
+
+func TestParse_EncryptedValueReferences(t *testing.T) {
+	type Database struct {
+		Password string `secret:"true"`
+		URL      string `ini:"url"`
+	}
+	type Config struct {
+		Database Database
+	}
+
+	aesgcm, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create AESGCM: %v", err)
+	}
+	password, err := EncryptValue(aesgcm, "hunter2")
+	if err != nil {
+		t.Fatalf("Failed to encrypt value: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		opts    []Option
+	}{
+		{"key reference", "[database]\npassword = " + password + "\nurl = pg://u:${database.password}@h\n", nil},
+		{"forward reference", "[database]\nurl = pg://u:${database.password}@h\npassword = " + password + "\n", nil},
+		{"extended interpolation", "[database]\npassword = " + password + "\nurl = pg://u:${password}@h\n", []Option{WithInterpolation(InterpolationExtended)}},
+	}
+
+	for _, test := range tests {
+		config := Config{}
+		if errs := Parse(strings.NewReader(test.content), &config, append(test.opts, WithDecrypter(aesgcm))...); errs != nil {
+			t.Errorf("%s: failed to parse: %v", test.name, errs)
+			continue
+		}
+		if config.Database.URL != "pg://u:hunter2@h" || config.Database.Password != "hunter2" {
+			t.Errorf("%s: expected references to get the plaintext, got %+v", test.name, config.Database)
+		}
+	}
+}
+
+func TestParse_EncryptedValueErrors(t *testing.T) {
+	failing := decrypterFunc(func([]byte) ([]byte, error) { return nil, errors.New("wrong key") })
+
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"[database]\npassword = enc:v1:not base64!\n", "error at line 2: invalid encrypted value: illegal base64 data at input byte 3"},
+		{"[database]\npassword = enc:v1:AAAA\n", "error at line 2: failed to decrypt value: wrong key"},
+	}
+
+	for _, test := range tests {
+		config := SecretConfig{}
+		errs := Parse(strings.NewReader(test.content), &config, WithDecrypter(failing))
+		if len(errs) != 1 || errs[0].Error() != test.expected {
+			t.Errorf("Expected error %q, got %v", test.expected, errs)
+		}
+	}
+}
+
+func TestWrite_EncryptedSecrets(t *testing.T) {
+	aesgcm, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create AESGCM: %v", err)
+	}
+
+	// Every pointer is set, since a nil pointer is written empty
+	source := newSecretConfig()
+	source.Replicas["eu"].Token = source.Database.Token
+
+	var buf bytes.Buffer
+	if err := Write(&buf, source, WithEncrypter(aesgcm)); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "hunter2") || strings.Contains(output, RedactedValue) || !strings.Contains(output, "password = enc:v1:") {
+		t.Fatalf("Expected secrets to be encrypted, got:\n%s", output)
+	}
+
+	config := SecretConfig{}
+	if errs := Parse(strings.NewReader(output), &config, WithDecrypter(aesgcm)); errs != nil {
+		t.Fatalf("Failed to parse written config: %v", errs)
+	}
+	expected := newSecretConfig()
+	if config.Database.Password != expected.Database.Password || *config.Database.Token != *expected.Database.Token {
+		t.Errorf("Expected secrets to round trip, got %+v", config.Database)
+	}
+	if len(config.Database.Keys) != 2 || config.Database.Keys[1] != "b" || config.Database.Pin != 1234 {
+		t.Errorf("Expected list and int secrets to round trip, got %+v", config.Database)
+	}
+	if config.Replicas["eu"].Password != "eu-secret" {
+		t.Errorf("Expected secrets in section maps to round trip, got %+v", config.Replicas["eu"])
+	}
+}
+
+type decrypterFunc func([]byte) ([]byte, error)
+
+func (f decrypterFunc) Decrypt(ciphertext []byte) ([]byte, error) {
+	return f(ciphertext)
+}
@@ -0,0 +1,194 @@
+package simpleini
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Include directives. A directive followed by ? is optional and skipped when
+// the file or directory does not exist.
+const (
+	includeDirective    = "!include"
+	includeDirDirective = "!includedir"
+)
+
+// includeDirExt is the extension of the files read by !includedir.
+const includeDirExt = ".ini"
+
+// defaultMaxIncludeDepth is the default maximum depth of nested includes.
+const defaultMaxIncludeDepth = 10
+
+// WithIncludes enables or disables include directives and include.path keys.
+// Includes are enabled by default; when disabled, they are an error.
+func WithIncludes(enabled bool) Option {
+	return func(o *options) {
+		o.includes = enabled
+	}
+}
+
+// WithIncludeRoots restricts included files to the root directories and their
+// subdirectories. Paths are compared after resolving symbolic links, so a link
+// pointing outside the roots is rejected.
+func WithIncludeRoots(roots ...string) Option {
+	return func(o *options) {
+		o.includeRoots = append(o.includeRoots, roots...)
+	}
+}
+
+// WithMaxIncludeDepth sets the maximum depth of nested includes, which is 10 by
+// default. A file included directly from the parsed configuration is at depth
+// 1, so a depth of 0 allows no includes at all.
+func WithMaxIncludeDepth(depth int) Option {
+	return func(o *options) {
+		o.maxIncludeDepth = depth
+	}
+}
+
+// resolveIncludePath resolves an included file name against the directory of
+// the including file, expanding a leading ~ to the home directory.
+func resolveIncludePath(includeFile, basePath string) string {
+	if rest, ok := strings.CutPrefix(includeFile, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(includeFile) {
+		includeFile = filepath.Join(basePath, includeFile)
+	}
+	return includeFile
+}
+
+// handleIncludeDirective processes an include directive. !include reads a file
+// or the files matching a glob pattern, and !includedir reads the .ini files of
+// a directory, both in lexical order.
+func (d *decoder) handleIncludeDirective(line, basePath string, depth int) ([]error, bool) {
+	directive, arg, ok := strings.Cut(line, " ")
+	if !ok {
+		return nil, false
+	}
+	directive, optional := strings.CutSuffix(directive, "?")
+	if directive != includeDirective && directive != includeDirDirective {
+		return nil, false
+	}
+
+	path := resolveIncludePath(strings.TrimSpace(arg), basePath)
+	if !d.opts.includes {
+		return []error{fmt.Errorf("includes are disabled: %s", path)}, true
+	}
+	if !hasGlobMeta(path) {
+		if err := d.checkInclude(path); err != nil {
+			return []error{err}, true
+		}
+	}
+	var files []string
+	var err error
+	if directive == includeDirDirective {
+		files, err = includeDirFiles(path)
+	} else {
+		files, err = includeFiles(path, optional)
+	}
+	if err != nil {
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return nil, true
+		}
+		return []error{err}, true
+	}
+
+	var includeErrors []error
+	for _, file := range files {
+		if err := d.checkInclude(file); err != nil {
+			includeErrors = append(includeErrors, err)
+			continue
+		}
+		includeErrors = append(includeErrors, d.parseFile(file, depth+1)...)
+	}
+	return includeErrors, true
+}
+
+// checkInclude returns an error if includes are disabled or the path is outside
+// the allowed roots once symbolic links are resolved.
+func (d *decoder) checkInclude(path string) error {
+	if !d.opts.includes {
+		return fmt.Errorf("includes are disabled: %s", path)
+	}
+	if len(d.opts.includeRoots) == 0 {
+		return nil
+	}
+	resolved, err := realPath(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve include: %w", err)
+	}
+	for _, root := range d.opts.includeRoots {
+		if resolvedRoot, err := realPath(root); err == nil && isWithinDir(resolved, resolvedRoot) {
+			return nil
+		}
+	}
+	return fmt.Errorf("include outside allowed roots: %s", path)
+}
+
+// realPath returns the absolute path with symbolic links resolved. A path that
+// does not exist is only made absolute, as there is no link to follow.
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		return abs, nil
+	}
+	return resolved, err
+}
+
+// isWithinDir checks if the path is the directory or inside it.
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// includeFiles returns the files matching the path of an !include directive,
+// which may be a glob pattern. A pattern matching no files is not an error,
+// and neither is a missing file if the directive is optional.
+func includeFiles(path string, optional bool) ([]string, error) {
+	if !hasGlobMeta(path) {
+		if optional {
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("failed to open file: %w", err)
+			}
+		}
+		return []string{path}, nil
+	}
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// includeDirFiles returns the .ini files in the directory of an !includedir
+// directive, sorted by name.
+func includeDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == includeDirExt {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// hasGlobMeta checks if the path contains any of the glob metacharacters
+// recognized by filepath.Match.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
@@ -0,0 +1,275 @@
+package simpleini
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type IncludeConfig struct {
+	AppName string
+	Plugins []string `sep:","`
+	Debug   bool
+}
+
+// writeIncludeFiles writes the files, given by their paths relative to dir.
+func writeIncludeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func TestParseFile_IncludeGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":         "app_name = MyApp\n!include conf.d/*.ini\n",
+		"conf.d/20-b.ini":  "plugins = b\n",
+		"conf.d/10-a.ini":  "plugins = a\n",
+		"conf.d/notes.txt": "plugins = ignored\n",
+	})
+
+	config := IncludeConfig{}
+	if errors := ParseFile(filepath.Join(dir, "main.ini"), &config); errors != nil {
+		t.Fatalf("Failed to parse INI with include glob: %v", errors)
+	}
+	// The files are read in order, so the last one sets the value
+	if config.AppName != "MyApp" || !reflect.DeepEqual(config.Plugins, []string{"b"}) {
+		t.Errorf("Expected the included files in lexical order, got %+v", config)
+	}
+}
+
+func TestParseFile_IncludeGlobNoMatches(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini": "!include conf.d/*.ini\napp_name = MyApp\n",
+	})
+
+	config := IncludeConfig{}
+	if errors := ParseFile(filepath.Join(dir, "main.ini"), &config); errors != nil {
+		t.Fatalf("Expected a glob without matches to be skipped, got %v", errors)
+	}
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be set, got %+v", config)
+	}
+}
+
+func TestParseFile_IncludeDir(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":             "!includedir conf.d\n",
+		"conf.d/b.ini":         "debug = true\n",
+		"conf.d/a.ini":         "app_name = FromDir\n",
+		"conf.d/c.ini~":        "app_name = Backup\n",
+		"conf.d/nested/d.ini":  "app_name = Nested\n",
+		"conf.d/README":        "not an ini file\n",
+		"conf.d/z.ini":         "plugins = x, y\n",
+		"conf.d/nested/e.conf": "app_name = Other\n",
+	})
+
+	config := IncludeConfig{}
+	if errors := ParseFile(filepath.Join(dir, "main.ini"), &config); errors != nil {
+		t.Fatalf("Failed to parse INI with include directory: %v", errors)
+	}
+	expected := IncludeConfig{AppName: "FromDir", Plugins: []string{"x", "y"}, Debug: true}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestParseFile_OptionalInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":  "!include? missing.ini\n!includedir? missing.d\n!include? local.ini\napp_name = MyApp\n",
+		"local.ini": "debug = true\n",
+	})
+
+	config := IncludeConfig{}
+	if errors := ParseFile(filepath.Join(dir, "main.ini"), &config); errors != nil {
+		t.Fatalf("Expected missing optional includes to be skipped, got %v", errors)
+	}
+	if config.AppName != "MyApp" || !config.Debug {
+		t.Errorf("Expected the existing optional include to be read, got %+v", config)
+	}
+}
+
+func TestParseFile_IncludeKeepsDefaults(t *testing.T) {
+	type Config struct {
+		Name    string   `default:"default_name"`
+		Plugins []string `sep:","`
+	}
+
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":     "name = custom\n!includedir conf.d\n",
+		"conf.d/a.ini": "plugins = a\n",
+		"conf.d/b.ini": "plugins = b\n",
+	})
+
+	config := Config{}
+	if errors := ParseFile(filepath.Join(dir, "main.ini"), &config); errors != nil {
+		t.Fatalf("Failed to parse INI with includes: %v", errors)
+	}
+	if config.Name != "custom" || !reflect.DeepEqual(config.Plugins, []string{"b"}) {
+		t.Errorf("Expected included files not to reset values to their defaults, got %+v", config)
+	}
+}
+
+func TestParseFile_IncludeSameFileTwice(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":         "!include common.ini\n!include conf.d/*.ini\n!include common.ini\n",
+		"common.ini":       "debug = true\n",
+		"conf.d/debug.ini": "debug = false\n!include ../common.ini\n",
+	})
+
+	config := IncludeConfig{}
+	if errors := ParseFile(filepath.Join(dir, "main.ini"), &config); errors != nil {
+		t.Fatalf("Expected a file to be included more than once, got %v", errors)
+	}
+	if !config.Debug {
+		t.Errorf("Expected the last include to set the value, got %+v", config)
+	}
+}
+
+func TestParseFile_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"missing file", "!include missing.ini\n", "failed to open file"},
+		{"missing directory", "!includedir missing.d\n", "failed to read directory"},
+		{"invalid pattern", "!include conf.d/[.ini\n", "invalid include pattern"},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		writeIncludeFiles(t, dir, map[string]string{"main.ini": test.content})
+
+		config := IncludeConfig{}
+		errors := ParseFile(filepath.Join(dir, "main.ini"), &config)
+		if errors == nil || !strings.Contains(errors[0].Error(), test.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", test.name, test.expected, errors)
+		}
+	}
+}
+
+func TestParseFile_IncludeRoots(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"app/main.ini":        "!include conf.d/*.ini\n",
+		"app/conf.d/base.ini": "app_name = MyApp\n",
+		"secret.ini":          "debug = true\n",
+	})
+	writeIncludeFiles(t, outside, map[string]string{"escape.ini": "debug = true\n"})
+	if err := os.Symlink(filepath.Join(outside, "escape.ini"), filepath.Join(dir, "app/conf.d/link.ini")); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	config := IncludeConfig{}
+	errors := ParseFile(filepath.Join(dir, "app/main.ini"), &config, WithIncludeRoots(filepath.Join(dir, "app")))
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "include outside allowed roots") {
+		t.Fatalf("Expected the symbolic link to be rejected, got %v", errors)
+	}
+	if config.AppName != "MyApp" || config.Debug {
+		t.Errorf("Expected only the file inside the root to be read, got %+v", config)
+	}
+
+	for _, line := range []string{"!include ../secret.ini", "!include " + filepath.Join(outside, "escape.ini"), "!includedir " + outside} {
+		writeIncludeFiles(t, dir, map[string]string{"app/main.ini": line + "\n"})
+		config := IncludeConfig{}
+		errors := ParseFile(filepath.Join(dir, "app/main.ini"), &config, WithIncludeRoots(filepath.Join(dir, "app")))
+		if errors == nil || !strings.Contains(errors[0].Error(), "include outside allowed roots") {
+			t.Errorf("%s: expected the include to be rejected, got %v", line, errors)
+		}
+	}
+}
+
+func TestParseFile_DisabledIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.ini":  "!include? other.ini\napp_name = MyApp\n",
+		"other.ini": "debug = true\n",
+	})
+
+	config := IncludeConfig{}
+	errors := ParseFile(filepath.Join(dir, "main.ini"), &config, WithIncludes(false))
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "includes are disabled") {
+		t.Fatalf("Expected an error for the disabled include, got %v", errors)
+	}
+	if config.AppName != "MyApp" || config.Debug {
+		t.Errorf("Expected the include to be skipped, got %+v", config)
+	}
+}
+
+func TestParseFile_DisabledIncludePathKey(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"main.gitconfig":  "[include]\npath = other.gitconfig\n",
+		"other.gitconfig": "[user]\nname = Other\n",
+	})
+
+	config := struct{ User struct{ Name string } }{}
+	errors := ParseFile(filepath.Join(dir, "main.gitconfig"), &config, WithDialect(DialectGit), WithIncludes(false))
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "includes are disabled") {
+		t.Errorf("Expected an error for the disabled include, got %v", errors)
+	}
+}
+
+func TestParseFile_MaxIncludeDepthOption(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"a.ini": "!include b.ini\n",
+		"b.ini": "!include c.ini\n",
+		"c.ini": "app_name = Deep\n",
+	})
+
+	config := IncludeConfig{}
+	errors := ParseFile(filepath.Join(dir, "a.ini"), &config, WithMaxIncludeDepth(1))
+	if errors == nil || !strings.Contains(errors[0].Error(), "maximum include depth exceeded") {
+		t.Fatalf("Expected the maximum include depth to be exceeded, got %v", errors)
+	}
+	if errors := ParseFile(filepath.Join(dir, "a.ini"), &config, WithMaxIncludeDepth(2)); errors != nil {
+		t.Fatalf("Expected the includes to be read, got %v", errors)
+	}
+	if config.AppName != "Deep" {
+		t.Errorf("Expected app_name to be set, got %+v", config)
+	}
+}
+
+func TestParse_MaxIncludeDepthEntryPoints(t *testing.T) {
+	dir := t.TempDir()
+	writeIncludeFiles(t, dir, map[string]string{
+		"a.ini": "!include b.ini\n",
+		"b.ini": "app_name = Included\n",
+	})
+	parsers := map[string]func(opts ...Option) []error{
+		"Parse": func(opts ...Option) []error {
+			content := "!include " + filepath.Join(dir, "b.ini") + "\n"
+			return Parse(strings.NewReader(content), &IncludeConfig{}, opts...)
+		},
+		"ParseFile": func(opts ...Option) []error {
+			return ParseFile(filepath.Join(dir, "a.ini"), &IncludeConfig{}, opts...)
+		},
+	}
+
+	for name, parse := range parsers {
+		errors := parse(WithMaxIncludeDepth(0))
+		if errors == nil || !strings.Contains(errors[0].Error(), "maximum include depth exceeded") {
+			t.Errorf("%s: expected a depth of 0 to reject the include, got %v", name, errors)
+		}
+		if errors := parse(WithMaxIncludeDepth(1)); errors != nil {
+			t.Errorf("%s: expected a depth of 1 to allow the include, got %v", name, errors)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package simpleini
+
+import (
+	"fmt"
+	"strings"
+)
+
+// inheritsKey names the base section of the current section, as an alternative
+// to the [child : base] header syntax. It is only a directive when the section
+// has no field for it.
+const inheritsKey = "inherits"
+
+// sectionBase is the base section a section inherits its keys from.
+type sectionBase struct {
+	name string
+	line int
+}
+
+// cutSectionBase splits a section header such as worker.b : worker.a into the
+// section and the base section it inherits from. Colons inside quotes do not
+// separate a base.
+func cutSectionBase(header string) (section, base string, found bool) {
+	var quote byte
+	for i := 0; i < len(header); i++ {
+		c := header[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && c == '"':
+			quote = c
+		case quote == 0 && c == ':':
+			return strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]), true
+		}
+	}
+	return header, "", false
+}
+
+// setSectionBase records that the section inherits the keys it does not set
+// from the base section.
+func (d *decoder) setSectionBase(section, base string, lineNumber int) error {
+	name, ok := d.parseSectionHeader(base)
+	if !ok {
+		return fmt.Errorf("invalid base section name at line %d: %s", lineNumber, name)
+	}
+	d.bases[d.foldName(section)] = sectionBase{name: name, line: lineNumber}
+	return nil
+}
+
+// applyInheritance copies the keys each section inherits from its base
+// sections, in the order the sections were declared. Keys set in the section
+// itself are not copied. Inherited values are set like the values of the
+// section, and errors refer to the line in the base section.
+func (d *decoder) applyInheritance() []error {
+	if len(d.bases) == 0 {
+		return nil
+	}
+
+	own := make(map[string][]assignment)
+	for _, a := range d.assignments {
+		own[d.foldName(a.section)] = append(own[d.foldName(a.section)], a)
+	}
+
+	var errors []error
+	inherited := make(map[string][]assignment)
+	for _, section := range d.sectionOrder {
+		if _, ok := d.bases[d.foldName(section)]; !ok {
+			continue
+		}
+		assignments, err := d.inheritedAssignments(section, own, inherited, nil)
+		if err != nil {
+			// Report a cycle once, not again for each section in it
+			inherited[d.foldName(section)] = nil
+			errors = append(errors, err)
+			continue
+		}
+		for _, a := range assignments {
+			if a.deferred {
+				d.assignments = append(d.assignments, a)
+			} else if err := d.assign(a); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+	return errors
+}
+
+// inheritedAssignments returns the assignments the section inherits from its
+// base sections, rewritten for the section. The chain of sections being
+// visited detects cycles.
+func (d *decoder) inheritedAssignments(section string, own, inherited map[string][]assignment, chain []string) ([]assignment, error) {
+	if assignments, ok := inherited[d.foldName(section)]; ok {
+		return assignments, nil
+	}
+	base, ok := d.bases[d.foldName(section)]
+	if !ok {
+		return nil, nil
+	}
+	for _, visited := range chain {
+		if d.foldName(visited) == d.foldName(section) {
+			return nil, fmt.Errorf("inheritance cycle at line %d: %s", base.line, strings.Join(append(chain, section), " -> "))
+		}
+	}
+	if !d.sections[d.foldName(base.name)] {
+		return nil, fmt.Errorf("unknown base section at line %d: %s", base.line, base.name)
+	}
+
+	baseAssignments, err := d.inheritedAssignments(base.name, own, inherited, append(chain, section))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	for _, a := range own[d.foldName(section)] {
+		keys[d.foldName(a.key)] = true
+	}
+	var assignments []assignment
+	for _, a := range append(own[d.foldName(base.name)], baseAssignments...) {
+		if keys[d.foldName(a.key)] {
+			continue
+		}
+		if a.inheritedFrom == "" {
+			a.inheritedFrom = a.section
+		}
+		a.section = section
+		assignments = append(assignments, a)
+	}
+	inherited[d.foldName(section)] = assignments
+	return assignments, nil
+}
@@ -0,0 +1,172 @@
+package simpleini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type InheritWorker struct {
+	Queue       string
+	Concurrency int
+	Timeout     float64
+	Tags        []string `sep:","`
+}
+
+type InheritConfig struct {
+	Worker map[string]InheritWorker
+}
+
+func TestParse_SectionInheritance(t *testing.T) {
+	iniContent := `
+[worker.a]
+queue = default
+concurrency = 4
+timeout = 2.5
+tags = fast, cheap
+
+[worker.b : worker.a]
+queue = priority
+
+[worker.c]
+inherits = worker.b
+concurrency = 8
+
+; A section may inherit from a base declared later
+[worker.d : worker.e]
+
+[worker.e]
+queue = late
+`
+
+	config := InheritConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with inheritance: %v", errors)
+	}
+
+	expected := map[string]InheritWorker{
+		"a": {Queue: "default", Concurrency: 4, Timeout: 2.5, Tags: []string{"fast", "cheap"}},
+		"b": {Queue: "priority", Concurrency: 4, Timeout: 2.5, Tags: []string{"fast", "cheap"}},
+		"c": {Queue: "priority", Concurrency: 8, Timeout: 2.5, Tags: []string{"fast", "cheap"}},
+		"d": {Queue: "late"},
+		"e": {Queue: "late"},
+	}
+	if !reflect.DeepEqual(config.Worker, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config.Worker)
+	}
+}
+
+func TestParse_SectionInheritanceWithReferences(t *testing.T) {
+	iniContent := `
+[worker.a]
+queue = ${worker.a.prefix}-jobs
+prefix = a
+
+[worker.b : worker.a]
+prefix = b
+`
+
+	type Worker struct {
+		Queue  string
+		Prefix string
+	}
+	config := struct{ Worker map[string]Worker }{}
+	if errors := Parse(strings.NewReader(iniContent), &config); errors != nil {
+		t.Fatalf("Failed to parse INI with inheritance: %v", errors)
+	}
+	if config.Worker["b"].Queue != "a-jobs" || config.Worker["b"].Prefix != "b" {
+		t.Errorf("Unexpected worker b: %+v", config.Worker["b"])
+	}
+
+	config.Worker = nil
+	errors := Parse(strings.NewReader("[worker.a]\nqueue = ${prefix}-jobs\nprefix = a\n\n[worker.b : worker.a]\nprefix = b\n"), &config, WithInterpolation(InterpolationExtended))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with inheritance: %v", errors)
+	}
+	if config.Worker["b"].Queue != "b-jobs" {
+		t.Errorf("Expected inherited references to resolve in the inheriting section, got %+v", config.Worker["b"])
+	}
+}
+
+func TestParse_SectionInheritanceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			"cycle",
+			"[worker.a : worker.b]\n\n[worker.b : worker.a]\n",
+			[]string{"inheritance cycle at line 1: worker.a -> worker.b -> worker.a"},
+		},
+		{
+			"unknown base",
+			"[worker.a : worker.missing]\nqueue = x\n",
+			[]string{"unknown base section at line 1: worker.missing"},
+		},
+		{
+			"invalid base",
+			"[worker.a : bad base]\n",
+			[]string{"invalid base section name at line 1: bad base"},
+		},
+		{
+			"inherited invalid value",
+			"[worker.a]\nconcurrency = many\n\n[worker.b : worker.a]\nqueue = x\n",
+			[]string{
+				"error at line 2: invalid value for field type int: many",
+				"error at line 2 (inherited from worker.a by worker.b): invalid value for field type int: many",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		config := InheritConfig{}
+		errors := Parse(strings.NewReader(test.content), &config)
+		if len(errors) != len(test.expected) {
+			t.Errorf("%s: expected %d errors, got %v", test.name, len(test.expected), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.expected[i] {
+				t.Errorf("%s: expected error %q, got %q", test.name, test.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestParse_InheritsField(t *testing.T) {
+	type Section struct {
+		Inherits string
+		Name     string
+	}
+	config := struct{ Child Section }{}
+	if errors := Parse(strings.NewReader("[child]\ninherits = parent\nname = x\n"), &config); errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if config.Child.Inherits != "parent" {
+		t.Errorf("Expected an inherits field to be set as a value, got %+v", config.Child)
+	}
+}
+
+func TestCutSectionBase(t *testing.T) {
+	tests := []struct {
+		input   string
+		section string
+		base    string
+		found   bool
+	}{
+		{"worker.b : worker.a", "worker.b", "worker.a", true},
+		{"worker.b:worker.a", "worker.b", "worker.a", true},
+		{"worker", "worker", "", false},
+		{`remote "a:b"`, `remote "a:b"`, "", false},
+		{`remote "a:b" : base`, `remote "a:b"`, "base", true},
+	}
+
+	for _, test := range tests {
+		section, base, found := cutSectionBase(test.input)
+		if section != test.section || base != test.base || found != test.found {
+			t.Errorf("cutSectionBase(%q) = (%q, %q, %v); expected (%q, %q, %v)", test.input, section, base, found, test.section, test.base, test.found)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package simpleini
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Interpolation selects how values may reference other keys, following the
+// interpolation classes of Python's configparser. In every mode, ${section.key}
+// references a key by its dotted path, as in ${paths.base} or
+// ${server.logging.level}.
+type Interpolation int
+
+const (
+	// InterpolationNone only resolves ${section.key} references.
+	InterpolationNone Interpolation = iota
+	// InterpolationBasic replaces %(name)s with the value of the key name in the
+	// same section or in the [DEFAULT] section. %% is a literal percent sign.
+	InterpolationBasic
+	// InterpolationExtended replaces ${name} with the value of the key name in
+	// the same section or in the [DEFAULT] section, and ${section:name} with the
+	// value of a key in another section. $$ is a literal dollar sign. A name that
+	// is not a key is looked up in the environment, unless expansion is disabled,
+	// and $NAME is kept as written.
+	InterpolationExtended
+)
+
+// defaultSection is the normalized name of the section whose keys are visible
+// from every section, as in configparser.
+const defaultSection = "default"
+
+// WithInterpolation enables configparser references to other keys in values.
+func WithInterpolation(mode Interpolation) Option {
+	return func(o *options) {
+		o.interpolation = mode
+	}
+}
+
+// assignment is a value set on the config. Values with references are deferred
+// until the whole file and its includes have been read, so a key may reference
+// keys declared after it.
+type assignment struct {
+	section  string
+	key      string
+	index    string
+	indexed  bool
+	value    string
+	raw      bool // the value is not interpolated
+	expand   bool // environment variables are expanded
+	deferred bool
+	override bool // set by a section of the active profile
+	appended bool // a repeated key appended to its slice field
+	line     int
+
+	inheritedFrom string // base section the value was inherited from
+}
+
+// location describes where the value was read, including the section it was
+// inherited from.
+func (a *assignment) location() string {
+	if a.inheritedFrom != "" {
+		return fmt.Sprintf("line %d (inherited from %s by %s)", a.line, a.inheritedFrom, a.section)
+	}
+	return fmt.Sprintf("line %d", a.line)
+}
+
+// interpolationError is an error in the value at a location. It is kept
+// unchanged as it propagates through the keys that reference the value.
+type interpolationError struct {
+	location string
+	err      error
+}
+
+func (e *interpolationError) Error() string {
+	return fmt.Sprintf("error at %s: %v", e.location, e.err)
+}
+
+func (e *interpolationError) Unwrap() error {
+	return e.err
+}
+
+// keyRef identifies a key in a section.
+type keyRef struct {
+	section string
+	key     string
+}
+
+// keyRef returns the reference to the key in the section.
+func (d *decoder) keyRef(section, key string) keyRef {
+	return keyRef{d.foldName(section), d.foldName(key)}
+}
+
+// interpolator resolves references between assignments.
+type interpolator struct {
+	d        *decoder
+	values   map[keyRef]*assignment // last unindexed assignment of each key
+	resolved map[keyRef]string
+	failed   map[keyRef]error
+	visiting map[keyRef]bool
+	stack    []keyRef
+}
+
+// resolveDeferred interpolates and sets the deferred assignments in the order
+// they were read.
+func (d *decoder) resolveDeferred() []error {
+	if !slices.ContainsFunc(d.assignments, func(a assignment) bool { return a.deferred }) {
+		return nil
+	}
+
+	// Values of the active profile are set last to override the rest of the file
+	slices.SortStableFunc(d.assignments, func(a, b assignment) int {
+		switch {
+		case a.override == b.override:
+			return 0
+		case b.override:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	in := &interpolator{
+		d:        d,
+		values:   make(map[keyRef]*assignment),
+		resolved: make(map[keyRef]string),
+		failed:   make(map[keyRef]error),
+		visiting: make(map[keyRef]bool),
+	}
+	for i := range d.assignments {
+		if a := &d.assignments[i]; !a.indexed {
+			in.values[d.keyRef(a.section, a.key)] = a
+		}
+	}
+
+	// A failed reference is reported once, not again for each key using it
+	var errors []error
+	reported := make(map[error]bool)
+	for i := range d.assignments {
+		a := d.assignments[i]
+		if !a.deferred || in.superseded(&d.assignments[i]) {
+			continue
+		}
+		value, err := in.interpolate(&d.assignments[i])
+		if err == nil {
+			a.value = value
+			err = d.assign(a)
+		}
+		if err != nil && !reported[err] {
+			reported[err] = true
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// superseded reports whether a later assignment replaces the value of the
+// assignment, so that setting it after the file has been read would undo the
+// later one. Values appended to a slice are not replaced.
+func (in *interpolator) superseded(a *assignment) bool {
+	if a.indexed {
+		return false
+	}
+	last := in.values[in.d.keyRef(a.section, a.key)]
+	return last != a && !last.appended
+}
+
+// interpolate returns the value of the assignment with its references replaced.
+func (in *interpolator) interpolate(a *assignment) (string, error) {
+	if a.raw {
+		return a.value, nil
+	}
+	ref := in.d.keyRef(a.section, a.key)
+	if !a.indexed && in.values[ref] == a {
+		return in.resolve(ref)
+	}
+	return in.expand(a)
+}
+
+// resolve returns the interpolated value of a key, detecting cycles.
+func (in *interpolator) resolve(ref keyRef) (string, error) {
+	if value, ok := in.resolved[ref]; ok {
+		return value, nil
+	}
+	if err, ok := in.failed[ref]; ok {
+		return "", err
+	}
+	a := in.values[ref]
+	if a.raw || !a.deferred {
+		// The value is kept as written or was expanded as it was read
+		return in.decrypt(a, a.value)
+	}
+	if in.visiting[ref] {
+		return "", &interpolationError{a.location(), fmt.Errorf("interpolation cycle: %s", in.cycle(ref))}
+	}
+
+	in.visiting[ref] = true
+	in.stack = append(in.stack, ref)
+	value, err := in.expand(a)
+	in.stack = in.stack[:len(in.stack)-1]
+	delete(in.visiting, ref)
+	if err == nil {
+		// References get the plaintext of an enc:v1: value
+		value, err = in.decrypt(a, value)
+	}
+	if err == nil {
+		// Stop values from growing through nested references
+		if limitErr := in.d.opts.limits.checkValue(value); limitErr != nil {
+			err = &interpolationError{a.location(), limitErr}
+		}
+	}
+	if err != nil {
+		in.failed[ref] = err
+		return "", err
+	}
+	in.resolved[ref] = value
+	return value, nil
+}
+
+// decrypt decrypts the value of the assignment if it is an enc:v1: value.
+func (in *interpolator) decrypt(a *assignment, value string) (string, error) {
+	value, err := decryptValue(in.d.opts.decrypter, value)
+	if err != nil {
+		return "", &interpolationError{a.location(), err}
+	}
+	return value, nil
+}
+
+// cycle describes the chain of references from the first visit of ref back to it.
+func (in *interpolator) cycle(ref keyRef) string {
+	var names []string
+	for i := len(in.stack) - 1; i >= 0; i-- {
+		names = append([]string{in.name(in.stack[i])}, names...)
+		if in.stack[i] == ref {
+			break
+		}
+	}
+	return strings.Join(append(names, in.name(ref)), " -> ")
+}
+
+// name returns the key as it is referenced: section:key with configparser
+// interpolation, section.key otherwise, or the key alone at the top level.
+func (in *interpolator) name(ref keyRef) string {
+	// Use the spelling of the key in the file
+	section, key := ref.section, ref.key
+	if a := in.values[ref]; a != nil {
+		section, key = a.section, a.key
+	}
+	if section == "" {
+		return key
+	}
+	if in.d.opts.interpolation == InterpolationNone {
+		return section + "." + key
+	}
+	return section + ":" + key
+}
+
+// expand replaces the references in the value of the assignment. The values
+// substituted are counted as they are looked up, so that a value with many
+// references fails before it grows far beyond the limit.
+func (in *interpolator) expand(a *assignment) (string, error) {
+	budget := &expansionBudget{limits: in.d.opts.limits, remaining: in.d.opts.limits.maxValueLength()}
+	var value string
+	var err error
+	switch {
+	case in.d.opts.interpolation == InterpolationExtended:
+		value, err = expandExtended(a.value, budget.sectionLookup(in.sectionLookup(a.section)))
+	case a.expand:
+		value, err = substituteEnvVars(a.value, budget.lookup(in.envLookup))
+	default:
+		value, err = expandReferences(a.value, budget.lookup(in.pathLookup))
+	}
+	if err == nil && in.d.opts.interpolation == InterpolationBasic {
+		value, err = expandBasic(value, budget.sectionLookup(in.sectionLookup(a.section)))
+	}
+
+	var ie *interpolationError
+	if err != nil && !errors.As(err, &ie) {
+		err = &interpolationError{a.location(), err}
+	}
+	return value, err
+}
+
+// expansionBudget bounds the total length of the values substituted for the
+// references in a value.
+type expansionBudget struct {
+	limits    Limits
+	remaining int
+}
+
+// spend counts a value returned by a lookup against the budget.
+func (b *expansionBudget) spend(value string, ok bool, err error) (string, bool, error) {
+	if ok && err == nil {
+		if b.remaining -= len(value); b.remaining < 0 {
+			return "", false, b.limits.valueTooLong()
+		}
+	}
+	return value, ok, err
+}
+
+// lookup returns the lookup counting its values against the budget.
+func (b *expansionBudget) lookup(lookup func(name string) (string, bool, error)) func(string) (string, bool, error) {
+	return func(name string) (string, bool, error) {
+		return b.spend(lookup(name))
+	}
+}
+
+// sectionLookup returns the lookup counting its values against the budget.
+func (b *expansionBudget) sectionLookup(lookup func(section, name string) (string, bool, error)) func(string, string) (string, bool, error) {
+	return func(section, name string) (string, bool, error) {
+		return b.spend(lookup(section, name))
+	}
+}
+
+// sectionLookup returns a lookup for configparser references from a value in
+// the section. With extended interpolation, a name that is not a key in the
+// section is resolved as a dotted path if it has a dot, and is looked up in the
+// environment otherwise.
+func (in *interpolator) sectionLookup(section string) func(refSection, name string) (string, bool, error) {
+	return func(refSection, name string) (string, bool, error) {
+		if refSection != "" {
+			if value, ok, err := in.d.resolve(refSection + ":" + name); ok {
+				return value, true, err
+			}
+			return in.lookup(in.d.normalizeName(refSection), in.d.normalizeName(name))
+		}
+		value, ok, err := in.lookup(section, in.d.normalizeName(name))
+		if !ok && in.d.opts.interpolation == InterpolationExtended {
+			if strings.Contains(name, ".") {
+				return in.pathLookup(name)
+			}
+			if in.d.opts.expandEnv {
+				value, ok = in.d.opts.lookupEnv(name)
+			}
+		}
+		return value, ok, err
+	}
+}
+
+// envLookup resolves a name with a dot as a reference to another key, and any
+// other name with a resolver or as an environment variable.
+func (in *interpolator) envLookup(name string) (string, bool, error) {
+	if _, _, ok := strings.Cut(name, ":"); !ok && strings.Contains(name, ".") {
+		return in.pathLookup(name)
+	}
+	return in.d.lookupEnv(name)
+}
+
+// pathLookup returns the interpolated value of the key with the dotted path.
+func (in *interpolator) pathLookup(path string) (string, bool, error) {
+	section, key := splitKeyPath(path)
+	ref := in.d.keyRef(in.d.normalizeName(section), in.d.normalizeName(key))
+	if _, ok := in.values[ref]; !ok {
+		return "", false, nil
+	}
+	value, err := in.resolve(ref)
+	return value, true, err
+}
+
+// lookup returns the interpolated value of the key in the section, falling back
+// to the [DEFAULT] section and then to the top level.
+func (in *interpolator) lookup(section, key string) (string, bool, error) {
+	for _, s := range []string{section, defaultSection, ""} {
+		ref := in.d.keyRef(s, key)
+		if _, ok := in.values[ref]; ok {
+			value, err := in.resolve(ref)
+			return value, true, err
+		}
+	}
+	return "", false, nil
+}
+
+// expandBasic replaces %(name)s references in the value.
+func expandBasic(value string, lookup func(section, name string) (string, bool, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(value, '%')
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		b.WriteString(value[:i])
+		value = value[i:]
+
+		switch {
+		case strings.HasPrefix(value, "%%"):
+			b.WriteByte('%')
+			value = value[2:]
+		case strings.HasPrefix(value, "%("):
+			end := strings.Index(value, ")s")
+			if end < 0 {
+				return "", fmt.Errorf("bad interpolation syntax: %s", value)
+			}
+			name := value[2:end]
+			resolved, ok, err := lookup("", name)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", fmt.Errorf("unresolved reference '%%(%s)s'", name)
+			}
+			b.WriteString(resolved)
+			value = value[end+2:]
+		default:
+			return "", fmt.Errorf("'%%' must be followed by '%%' or '(': %s", value)
+		}
+	}
+}
+
+// expandExtended replaces ${name} and ${section:name} references in the value.
+func expandExtended(value string, lookup func(section, name string) (string, bool, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(value, '$')
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		b.WriteString(value[:i])
+		value = value[i:]
+
+		switch {
+		case strings.HasPrefix(value, "$$"):
+			b.WriteByte('$')
+			value = value[2:]
+		case strings.HasPrefix(value, "${"):
+			end := strings.IndexByte(value, '}')
+			if end < 0 {
+				return "", fmt.Errorf("bad interpolation syntax: %s", value)
+			}
+			ref := value[2:end]
+			section, name, qualified := strings.Cut(ref, ":")
+			if !qualified {
+				section, name = "", ref
+			}
+			resolved, ok, err := lookup(section, name)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", fmt.Errorf("unresolved reference '${%s}'", ref)
+			}
+			b.WriteString(resolved)
+			value = value[end+1:]
+		default:
+			b.WriteByte('$')
+			value = value[1:]
+		}
+	}
+}
+
+// expandReferences replaces ${section.key} references in the value, and $$ with
+// a literal $. References without a dot are environment variables and are kept
+// as written.
+func expandReferences(value string, lookup func(path string) (string, bool, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(value, '$')
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		b.WriteString(value[:i])
+		value = value[i:]
+
+		end := strings.IndexByte(value, '}')
+		switch {
+		case strings.HasPrefix(value, "$$"):
+			b.WriteByte('$')
+			value = value[2:]
+		case strings.HasPrefix(value, "${") && end >= 0:
+			path := value[2:end]
+			if !strings.Contains(path, ".") {
+				b.WriteString(value[:end+1])
+			} else {
+				resolved, ok, err := lookup(path)
+				if err != nil {
+					return "", err
+				}
+				if !ok {
+					return "", fmt.Errorf("unresolved reference '${%s}'", path)
+				}
+				b.WriteString(resolved)
+			}
+			value = value[end+1:]
+		default:
+			b.WriteByte('$')
+			value = value[1:]
+		}
+	}
+}
+
+// containsReference reports whether the value has a ${section.key} reference.
+func containsReference(value string) bool {
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			return false
+		}
+		value = value[i+2:]
+		end := strings.IndexByte(value, '}')
+		if end < 0 {
+			return false
+		}
+		if strings.Contains(value[:end], ".") {
+			return true
+		}
+		value = value[end+1:]
+	}
+}
+
+// splitKeyPath splits a dotted path into the section and the key after the last dot.
+func splitKeyPath(path string) (section, key string) {
+	i := strings.LastIndexByte(path, '.')
+	return path[:i], path[i+1:]
+}
@@ -0,0 +1,453 @@
+package simpleini
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type InterpolationPaths struct {
+	Base string
+	Logs string
+	Data string
+}
+
+type InterpolationServer struct {
+	Host string
+	Port int
+	URL  string `ini:"url"`
+}
+
+type InterpolationConfig struct {
+	Paths  InterpolationPaths  `ini:"paths"`
+	Server InterpolationServer `ini:"server"`
+}
+
+func TestParse_BasicInterpolation(t *testing.T) {
+	iniContent := `
+[DEFAULT]
+base = /srv
+
+[paths]
+logs = %(base)s/logs
+data = %(logs)s/../data 100%%
+
+[server]
+url = http://%(host)s:%(port)s/
+host = localhost
+port = 8080
+`
+
+	config := InterpolationConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithInterpolation(InterpolationBasic))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+
+	if config.Paths.Logs != "/srv/logs" {
+		t.Errorf("Expected logs to use the DEFAULT section, got %q", config.Paths.Logs)
+	}
+	if config.Paths.Data != "/srv/logs/../data 100%" {
+		t.Errorf("Unexpected data: %q", config.Paths.Data)
+	}
+	if config.Server.URL != "http://localhost:8080/" || config.Server.Port != 8080 {
+		t.Errorf("Expected forward references to resolve, got %+v", config.Server)
+	}
+}
+
+func TestParse_ExtendedInterpolation(t *testing.T) {
+	os.Setenv("TEST_INTERPOLATION_HOME", "/home/test")
+	defer os.Unsetenv("TEST_INTERPOLATION_HOME")
+
+	iniContent := `
+[paths]
+base = ${TEST_INTERPOLATION_HOME}
+logs = ${base}/logs
+data = '''${base} is kept'''
+
+[server]
+host = ${paths:base}
+url = $$HOME ${host}:$PORT
+`
+
+	config := InterpolationConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithInterpolation(InterpolationExtended), WithContinuation(ContinuationHeredoc))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+
+	if config.Paths.Logs != "/home/test/logs" {
+		t.Errorf("Unexpected logs: %q", config.Paths.Logs)
+	}
+	if config.Paths.Data != "${base} is kept" {
+		t.Errorf("Expected raw block to be kept, got %q", config.Paths.Data)
+	}
+	if config.Server.Host != "/home/test" {
+		t.Errorf("Expected reference to another section, got %q", config.Server.Host)
+	}
+	if config.Server.URL != "$HOME /home/test:$PORT" {
+		t.Errorf("Unexpected url: %q", config.Server.URL)
+	}
+}
+
+func TestParse_DefaultSectionWithoutField(t *testing.T) {
+	iniContent := `
+[DEFAULT]
+base = /srv
+raw = '''${base}'''
+unused = 1
+
+[paths]
+logs = ${base}/logs
+data = ${raw}
+`
+
+	config := InterpolationConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithInterpolation(InterpolationExtended), WithContinuation(ContinuationHeredoc))
+	if errors != nil {
+		t.Fatalf("Expected the [DEFAULT] section not to need a field, got %v", errors)
+	}
+	if config.Paths.Logs != "/srv/logs" || config.Paths.Data != "${base}" {
+		t.Errorf("Expected references to the [DEFAULT] section, got %+v", config.Paths)
+	}
+
+	errors = Parse(strings.NewReader("[DEFAULT]\nbase = %(missing)s\n"), &config, WithInterpolation(InterpolationBasic))
+	if len(errors) != 1 || errors[0].Error() != "error at line 2: unresolved reference '%(missing)s'" {
+		t.Errorf("Expected errors in the [DEFAULT] section to be reported, got %v", errors)
+	}
+}
+
+func TestParse_InterpolationErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     Interpolation
+		content  string
+		expected []string
+	}{
+		{
+			"cycle",
+			InterpolationBasic,
+			"[paths]\nbase = %(logs)s\nlogs = %(data)s\ndata = %(base)s\n",
+			[]string{"error at line 2: interpolation cycle: paths:base -> paths:logs -> paths:data -> paths:base"},
+		},
+		{
+			"self reference",
+			InterpolationExtended,
+			"[paths]\nbase = ${base}\n",
+			[]string{"error at line 2: interpolation cycle: paths:base -> paths:base"},
+		},
+		{
+			"unresolved",
+			InterpolationExtended,
+			"[paths]\nbase = /srv\nlogs = ${missing:base}\n",
+			[]string{"error at line 3: unresolved reference '${missing:base}'"},
+		},
+		{
+			"unresolved through another key",
+			InterpolationBasic,
+			"[paths]\nbase = %(missing)s\nlogs = %(base)s\n",
+			[]string{"error at line 2: unresolved reference '%(missing)s'"},
+		},
+		{
+			"bad syntax",
+			InterpolationBasic,
+			"[paths]\nbase = 100%\n",
+			[]string{"error at line 2: '%' must be followed by '%' or '(': %"},
+		},
+		{
+			"invalid value",
+			InterpolationBasic,
+			"[server]\nhost = x\nport = %(host)s\n",
+			[]string{"error at line 3: invalid value for field type int: x"},
+		},
+	}
+
+	for _, test := range tests {
+		config := InterpolationConfig{}
+		errors := Parse(strings.NewReader(test.content), &config, WithInterpolation(test.mode))
+		if len(errors) != len(test.expected) {
+			t.Errorf("%s: expected %d errors, got %v", test.name, len(test.expected), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.expected[i] {
+				t.Errorf("%s: expected error %q, got %q", test.name, test.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestParse_InterpolationDisabled(t *testing.T) {
+	config := InterpolationConfig{}
+	errors := Parse(strings.NewReader("[paths]\nbase = /srv\nlogs = %(base)s\n"), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+	if config.Paths.Logs != "%(base)s" {
+		t.Errorf("Expected references to be kept without interpolation, got %q", config.Paths.Logs)
+	}
+}
+
+type ReferenceLogging struct {
+	Level string
+	File  string
+}
+
+type ReferenceServer struct {
+	Host    string
+	Port    int
+	URL     string `ini:"url"`
+	Logging ReferenceLogging
+}
+
+type ReferenceConfig struct {
+	Name   string
+	Paths  InterpolationPaths
+	Server ReferenceServer
+}
+
+func TestParse_KeyReferences(t *testing.T) {
+	os.Setenv("TEST_REFERENCE_HOME", "/home/test")
+	defer os.Unsetenv("TEST_REFERENCE_HOME")
+
+	iniContent := `
+name = app-${server.logging.level}
+
+[server]
+url = http://${server.host}:${server.port}/
+port = ${paths.data}
+host = localhost
+
+[server.logging]
+level = debug
+file = ${paths.logs}/server.log
+
+[paths]
+base = ${TEST_REFERENCE_HOME}
+logs = ${paths.base}/logs
+data = 8080
+`
+
+	config := ReferenceConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+
+	if config.Name != "app-debug" {
+		t.Errorf("Unexpected name: %q", config.Name)
+	}
+	if config.Server.URL != "http://localhost:8080/" || config.Server.Port != 8080 {
+		t.Errorf("Expected forward references to resolve and convert, got %+v", config.Server)
+	}
+	if config.Paths.Logs != "/home/test/logs" {
+		t.Errorf("Unexpected logs: %q", config.Paths.Logs)
+	}
+	if config.Server.Logging.File != "/home/test/logs/server.log" {
+		t.Errorf("Unexpected log file: %q", config.Server.Logging.File)
+	}
+}
+
+func TestParse_KeyReferencesAcrossIncludes(t *testing.T) {
+	dir := t.TempDir()
+	includeFile := filepath.Join(dir, "paths.ini")
+	if err := os.WriteFile(includeFile, []byte("[paths]\nbase = /srv\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+	mainFile := filepath.Join(dir, "main.ini")
+	if err := os.WriteFile(mainFile, []byte("[paths]\nlogs = ${paths.base}/logs\n\n!include paths.ini\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write main file: %v", err)
+	}
+
+	config := ReferenceConfig{}
+	if errors := ParseFile(mainFile, &config); errors != nil {
+		t.Fatalf("Failed to parse INI file: %v", errors)
+	}
+	if config.Paths.Logs != "/srv/logs" {
+		t.Errorf("Expected reference to a key in an included file, got %q", config.Paths.Logs)
+	}
+}
+
+func TestParse_KeyReferencesOverridden(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"literal after reference", "[paths]\nbase = /srv\nlogs = ${paths.base}/logs\nlogs = /var/log\n", "/var/log"},
+		{"reference after literal", "[paths]\nbase = /srv\nlogs = /var/log\nlogs = ${paths.base}/logs\n", "/srv/logs"},
+		{"reference after reference", "[paths]\nbase = /srv\nlogs = ${paths.base}/logs\nlogs = ${paths.base}/log\n", "/srv/log"},
+	}
+
+	for _, test := range tests {
+		config := ReferenceConfig{}
+		if errors := Parse(strings.NewReader(test.content), &config); errors != nil {
+			t.Errorf("%s: failed to parse INI content: %v", test.name, errors)
+			continue
+		}
+		if config.Paths.Logs != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, config.Paths.Logs)
+		}
+	}
+}
+
+func TestParse_KeyReferenceOverriddenByInclude(t *testing.T) {
+	dir := t.TempDir()
+	includeFile := filepath.Join(dir, "override.ini")
+	if err := os.WriteFile(includeFile, []byte("[paths]\nlogs = /var/log\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+	mainFile := filepath.Join(dir, "main.ini")
+	if err := os.WriteFile(mainFile, []byte("[paths]\nbase = /srv\nlogs = ${paths.base}/logs\n\n!include override.ini\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write main file: %v", err)
+	}
+
+	config := ReferenceConfig{}
+	if errors := ParseFile(mainFile, &config); errors != nil {
+		t.Fatalf("Failed to parse INI file: %v", errors)
+	}
+	if config.Paths.Logs != "/var/log" {
+		t.Errorf("Expected the included value to override the reference, got %q", config.Paths.Logs)
+	}
+}
+
+func TestParse_KeyReferencesWithoutEnvExpansion(t *testing.T) {
+	config := ReferenceConfig{}
+	errors := Parse(strings.NewReader("[paths]\nbase = ${HOME}\nlogs = ${paths.base}/logs\n"), &config, WithDialect(DialectSystemd))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+	if config.Paths.Logs != "${HOME}/logs" {
+		t.Errorf("Expected references to resolve without environment expansion, got %q", config.Paths.Logs)
+	}
+}
+
+func TestParse_KeyReferenceGrowth(t *testing.T) {
+	type Config struct {
+		S struct {
+			K0, K1, K2, K3, K4, K5, K6, K7, K8, K9, K10, K11, K12, K13, K14, K15, K16, K17, K18, K19 string
+		}
+	}
+
+	// Each key doubles the previous one, which would reach 64 MiB by k19
+	var b strings.Builder
+	b.WriteString("[s]\nk0 = " + strings.Repeat("x", 64) + "\n")
+	for i := 1; i < 20; i++ {
+		fmt.Fprintf(&b, "k%d = ${s.k%d}${s.k%d}\n", i, i-1, i-1)
+	}
+
+	config := Config{}
+	errors := Parse(strings.NewReader(b.String()), &config)
+	expected := "error at line 17: value exceeds the maximum length of 1048576 bytes"
+	if len(errors) != 1 || errors[0].Error() != expected {
+		t.Fatalf("Expected error %q, got %v", expected, errors)
+	}
+	if len(config.S.K14) != DefaultMaxValueLength || config.S.K15 != "" || config.S.K19 != "" {
+		t.Errorf("Expected values to stop growing at the default limit, got %d and %d bytes", len(config.S.K14), len(config.S.K19))
+	}
+
+	// A single value with many references is stopped while it is expanded
+	b.Reset()
+	b.WriteString("[s]\nk0 = " + strings.Repeat("x", 1024) + "\nk1 = ")
+	b.WriteString(strings.Repeat("${s.k0}", 2000))
+	config = Config{}
+	errors = Parse(strings.NewReader(b.String()), &config, WithLimits(Limits{MaxValueLength: 1 << 16}))
+	expected = "error at line 3: value exceeds the maximum length of 65536 bytes"
+	if len(errors) != 1 || errors[0].Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, errors)
+	}
+}
+
+func TestParse_KeyReferenceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			"cycle",
+			"[paths]\nbase = ${paths.logs}\nlogs = ${paths.base}/logs\n",
+			[]string{"error at line 2: interpolation cycle: paths.base -> paths.logs -> paths.base"},
+		},
+		{
+			"unresolved",
+			"[paths]\nbase = /srv\n\n[server]\nhost = ${paths.host}\n",
+			[]string{"error at line 5: unresolved reference '${paths.host}'"},
+		},
+		{
+			"invalid value",
+			"[paths]\nbase = x\n\n[server]\nport = ${paths.base}\n",
+			[]string{"error at line 5: invalid value for field type int: x"},
+		},
+	}
+
+	for _, test := range tests {
+		config := ReferenceConfig{}
+		errors := Parse(strings.NewReader(test.content), &config)
+		if len(errors) != len(test.expected) {
+			t.Errorf("%s: expected %d errors, got %v", test.name, len(test.expected), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.expected[i] {
+				t.Errorf("%s: expected error %q, got %q", test.name, test.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestContainsReference(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"${paths.base}/logs", true},
+		{"${HOME} and ${a.b}", true},
+		{"${HOME}/logs", false},
+		{"${a.b", false},
+		{"a.b", false},
+	}
+
+	for _, test := range tests {
+		result := containsReference(test.input)
+		if result != test.expected {
+			t.Errorf("containsReference(%q) = %v; expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestParse_EscapedKeyReference(t *testing.T) {
+	config := ReferenceConfig{}
+	errors := Parse(strings.NewReader("[paths]\nbase = /srv\nlogs = $${paths.base} is ${paths.base}\n"), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+	if config.Paths.Logs != "${paths.base} is /srv" {
+		t.Errorf("Expected $$ to escape the reference, got %q", config.Paths.Logs)
+	}
+}
+
+func TestParse_EscapedDollarWithoutExpansion(t *testing.T) {
+	type Paths struct {
+		Base string
+		Logs string
+		Data string `expand:"false"`
+	}
+	type Config struct {
+		Paths Paths
+	}
+
+	iniContent := "[paths]\nbase = $$5\nlogs = $$5 ${paths.base}\ndata = $$HOME\n"
+	for _, opts := range [][]Option{nil, {WithEnvExpansion(false)}} {
+		config := Config{}
+		if errors := Parse(strings.NewReader(iniContent), &config, opts...); errors != nil {
+			t.Fatalf("Failed to parse INI content: %v", errors)
+		}
+		expected := Paths{Base: "$5", Logs: "$5 $5", Data: "$HOME"}
+		if config.Paths != expected {
+			t.Errorf("Expected $$ to be a literal $ in every value, got %+v", config.Paths)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package simpleini
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxLineLength is the maximum length of a line in bytes unless
+// Limits.MaxLineLength is set.
+const DefaultMaxLineLength = bufio.MaxScanTokenSize
+
+// DefaultMaxValueLength is the maximum length of a value in bytes unless
+// Limits.MaxValueLength is set.
+const DefaultMaxValueLength = 1 << 20
+
+// Limits bounds the input accepted from config files that may not be trusted.
+// A zero field means no limit, except for MaxLineLength and MaxValueLength,
+// which default to DefaultMaxLineLength and DefaultMaxValueLength. Reading stops
+// at the first limit exceeded.
+type Limits struct {
+	MaxLineLength  int   // bytes in a single line
+	MaxFileSize    int64 // bytes read from each file or reader
+	MaxKeys        int   // key assignments, including repeated and indexed keys
+	MaxSections    int   // distinct sections
+	MaxValueLength int   // bytes in a value, including multiline values and expansions
+	MaxSliceLength int   // elements in a slice grown by key[N] or repeated keys
+}
+
+// WithLimits sets the limits on the input.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// errInputTooLarge is returned by a sizeLimitReader once the limit is exceeded.
+var errInputTooLarge = errors.New("input too large")
+
+// sizeLimitReader reads up to remaining bytes from r, and fails if there are
+// more.
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.r.Read(p)
+	if int64(n) > r.remaining {
+		n, r.exceeded = int(r.remaining), true
+		err = errInputTooLarge
+	}
+	r.remaining -= int64(n)
+	return n, err
+}
+
+// maxLineLength returns the maximum length of a line.
+func (l Limits) maxLineLength() int {
+	if l.MaxLineLength > 0 {
+		return l.MaxLineLength
+	}
+	return DefaultMaxLineLength
+}
+
+// newLineScanner returns a scanner over the lines of the reader, transcoded to
+// UTF-8, that enforces the maximum line and file sizes.
+func newLineScanner(reader io.Reader, limits Limits, encoding Encoding) *bufio.Scanner {
+	limited := &sizeLimitReader{r: reader, remaining: limits.MaxFileSize}
+	if limits.MaxFileSize > 0 {
+		reader = limited
+	}
+	scanner := bufio.NewScanner(decodeInput(reader, encoding))
+	// Leave room for the line ending, so that the length of the line itself is checked
+	scanner.Buffer(nil, limits.maxLineLength()+2)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		// Drop the line cut off by the size limit
+		if atEOF && limited.exceeded && bytes.IndexAny(data, "\r\n") < 0 {
+			return 0, nil, errInputTooLarge
+		}
+		return scanLines(data, atEOF)
+	})
+	return scanner
+}
+
+// checkLine returns an error if the line is longer than the limit.
+func (l Limits) checkLine(line string, lineNumber int) error {
+	if len(line) > l.maxLineLength() {
+		return l.lineTooLong(lineNumber)
+	}
+	return nil
+}
+
+func (l Limits) lineTooLong(lineNumber int) error {
+	return fmt.Errorf("line %d exceeds the maximum length of %d bytes", lineNumber, l.maxLineLength())
+}
+
+// scanError describes the error that stopped a scanner after the line.
+func (l Limits) scanError(err error, lineNumber int) error {
+	switch err {
+	case errInputTooLarge:
+		return fmt.Errorf("input exceeds the maximum size of %d bytes", l.MaxFileSize)
+	case bufio.ErrTooLong:
+		return l.lineTooLong(lineNumber + 1)
+	default:
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+}
+
+// checkValue returns an error if the value is longer than the limit.
+func (l Limits) checkValue(value string) error {
+	if len(value) > l.maxValueLength() {
+		return l.valueTooLong()
+	}
+	return nil
+}
+
+// maxValueLength returns the maximum length of a value.
+func (l Limits) maxValueLength() int {
+	if l.MaxValueLength > 0 {
+		return l.MaxValueLength
+	}
+	return DefaultMaxValueLength
+}
+
+func (l Limits) valueTooLong() error {
+	return fmt.Errorf("value exceeds the maximum length of %d bytes", l.maxValueLength())
+}
+
+// checkSliceLength returns an error if a slice field would grow longer than the
+// limit.
+func (l Limits) checkSliceLength(n int, key string) error {
+	if l.MaxSliceLength > 0 && n > l.MaxSliceLength {
+		return fmt.Errorf("slice field '%s' exceeds the maximum length of %d elements", key, l.MaxSliceLength)
+	}
+	return nil
+}
+
+// exceedLimit records that a limit was exceeded, which stops reading, and
+// returns the error.
+func (d *decoder) exceedLimit(err error) error {
+	d.limitExceeded = true
+	return err
+}
+
+// checkKeyCount returns an error if another key would exceed the limit.
+func (d *decoder) checkKeyCount(lineNumber int) error {
+	if max := d.opts.limits.MaxKeys; max > 0 && d.keyCount >= max {
+		return d.exceedLimit(fmt.Errorf("too many keys at line %d: the maximum is %d", lineNumber, max))
+	}
+	return nil
+}
+
+// checkSectionCount returns an error if another section would exceed the limit.
+func (d *decoder) checkSectionCount(lineNumber int) error {
+	if max := d.opts.limits.MaxSections; max > 0 && len(d.sections) >= max {
+		return d.exceedLimit(fmt.Errorf("too many sections at line %d: the maximum is %d", lineNumber, max))
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package simpleini
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type LimitsConfig struct {
+	Name   string
+	Values []string `sep:","`
+	Server struct {
+		Host string
+		Port int
+	}
+	Client struct {
+		Host string
+	}
+}
+
+func TestParse_Limits(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		limits   Limits
+		expected string
+	}{
+		{
+			"line length",
+			"name = short\nname = " + strings.Repeat("x", 100) + "\n",
+			Limits{MaxLineLength: 64},
+			"line 2 exceeds the maximum length of 64 bytes",
+		},
+		{
+			"default line length",
+			"name = " + strings.Repeat("x", DefaultMaxLineLength) + "\n",
+			Limits{},
+			"line 1 exceeds the maximum length of 65536 bytes",
+		},
+		{
+			"file size",
+			"name = a\nname = b\nname = c\n",
+			Limits{MaxFileSize: 12},
+			"input exceeds the maximum size of 12 bytes",
+		},
+		{
+			"keys",
+			"name = a\n[server]\nhost = b\nport = 1\n",
+			Limits{MaxKeys: 2},
+			"too many keys at line 4: the maximum is 2",
+		},
+		{
+			"sections",
+			"[server]\nhost = a\n[client]\nhost = b\n",
+			Limits{MaxSections: 1},
+			"too many sections at line 3: the maximum is 1",
+		},
+		{
+			"value length",
+			"name = " + strings.Repeat("x", 20) + "\n",
+			Limits{MaxValueLength: 16},
+			"error at line 1: value exceeds the maximum length of 16 bytes",
+		},
+		{
+			"multiline value length",
+			"name = start\n" + strings.Repeat("  xxxxxxxx\n", 100),
+			Limits{MaxValueLength: 64},
+			"error at line 1: value exceeds the maximum length of 64 bytes",
+		},
+		{
+			"default value length",
+			"name = start\n" + strings.Repeat("  "+strings.Repeat("x", 60000)+"\n", 20),
+			Limits{},
+			"error at line 1: value exceeds the maximum length of 1048576 bytes",
+		},
+		{
+			"expanded value length",
+			"name = ${server.host}${server.host}${server.host}\n[server]\nhost = xxxxxxxx\n",
+			Limits{MaxValueLength: 16},
+			"error at line 1: value exceeds the maximum length of 16 bytes",
+		},
+	}
+
+	for _, test := range tests {
+		config := LimitsConfig{}
+		errors := Parse(strings.NewReader(test.content), &config, WithLimits(test.limits))
+		if len(errors) != 1 || errors[0].Error() != test.expected {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expected, errors)
+		}
+	}
+}
+
+func TestParse_LimitsStopReading(t *testing.T) {
+	iniContent := "[server]\nhost = a\n[client]\nhost = b\nname = after\n"
+
+	config := LimitsConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithLimits(Limits{MaxSections: 1}))
+	if len(errors) != 1 {
+		t.Fatalf("Expected one error, got %v", errors)
+	}
+	if config.Server.Host != "a" || config.Client.Host != "" || config.Name != "" {
+		t.Errorf("Expected reading to stop at the limit, got %+v", config)
+	}
+}
+
+func TestParse_WithinLimits(t *testing.T) {
+	iniContent := "name = app\nvalues = a, b\n[server]\nhost = localhost\nport = 80\n"
+
+	config := LimitsConfig{}
+	limits := Limits{MaxLineLength: 32, MaxFileSize: 128, MaxKeys: 4, MaxSections: 1, MaxValueLength: 16}
+	if errors := Parse(strings.NewReader(iniContent), &config, WithLimits(limits)); errors != nil {
+		t.Fatalf("Expected the input to be within the limits, got %v", errors)
+	}
+	if config.Name != "app" || config.Server.Port != 80 {
+		t.Errorf("Expected the config to be set, got %+v", config)
+	}
+}
+
+func TestParse_ReadError(t *testing.T) {
+	readErr := errors.New("disk failure")
+	config := LimitsConfig{}
+	errs := Parse(iotest.ErrReader(readErr), &config)
+	if len(errs) != 1 || !errors.Is(errs[0], readErr) || !strings.HasPrefix(errs[0].Error(), "failed to read input") {
+		t.Errorf("Expected the read error to be reported, got %v", errs)
+	}
+}
+
+func TestParseDocument_LineLimit(t *testing.T) {
+	_, err := ParseDocument(strings.NewReader("name = "+strings.Repeat("x", 100)+"\n"), WithLimits(Limits{MaxLineLength: 64}))
+	if err == nil || err.Error() != "line 1 exceeds the maximum length of 64 bytes" {
+		t.Errorf("Expected a line length error, got %v", err)
+	}
+}
+
+func TestParse_SliceLengthLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		option   Option
+		expected string
+		values   []string
+	}{
+		{
+			"index",
+			"values[0] = a\nvalues[4] = b\nvalues[] = c\n",
+			WithArrayKeys(),
+			"error at line 2: slice field 'values' exceeds the maximum length of 4 elements",
+			[]string{"a", "c"},
+		},
+		{
+			"repeated keys",
+			"values = a, b\nvalues = c\nvalues = d, e\n",
+			WithDuplicateKeys(DuplicateAppend),
+			"error at line 3: slice field 'values' exceeds the maximum length of 4 elements",
+			[]string{"a", "b", "c"},
+		},
+	}
+
+	for _, test := range tests {
+		config := LimitsConfig{}
+		errors := Parse(strings.NewReader(test.content), &config, test.option, WithLimits(Limits{MaxSliceLength: 4}))
+		if len(errors) != 1 || errors[0].Error() != test.expected {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expected, errors)
+		}
+		if !reflect.DeepEqual(config.Values, test.values) {
+			t.Errorf("%s: expected values %v, got %v", test.name, test.values, config.Values)
+		}
+	}
+}
+
+func TestParse_MultilineKeyLimit(t *testing.T) {
+	iniContent := "values = a\n  b\n  c\nname = app\n"
+
+	config := LimitsConfig{}
+	if errors := Parse(strings.NewReader(iniContent), &config, WithLimits(Limits{MaxKeys: 2})); errors != nil {
+		t.Fatalf("Expected a multiline value to count as one key, got %v", errors)
+	}
+	if config.Name != "app" || len(config.Values) != 3 {
+		t.Errorf("Expected the config to be set, got %+v", config)
+	}
+}
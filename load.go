@@ -0,0 +1,64 @@
+package simpleini
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// checkStructType returns an error if T is not a struct type.
+func checkStructType[T any]() error {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("simpleini: type parameter must be a struct, got %s", t)
+	}
+	return nil
+}
+
+// Load parses the INI content from an io.Reader into a new value of type T.
+// Default values are applied before the content is read, and all parse errors
+// are joined into a single error.
+func Load[T any](r io.Reader, opts ...Option) (T, error) {
+	var config T
+	if err := checkStructType[T](); err != nil {
+		return config, err
+	}
+	if errs := Parse(r, &config, opts...); errs != nil {
+		return config, errors.Join(errs...)
+	}
+	return config, nil
+}
+
+// LoadFile parses the named INI file into a new value of type T.
+// Relative include directives are resolved against the directory of the file.
+func LoadFile[T any](filename string, opts ...Option) (T, error) {
+	var config T
+	if err := checkStructType[T](); err != nil {
+		return config, err
+	}
+	if errs := ParseFile(filename, &config, opts...); errs != nil {
+		return config, errors.Join(errs...)
+	}
+	return config, nil
+}
+
+// MustLoad is like Load but panics if the content cannot be parsed.
+// It is intended for use in main packages and tests.
+func MustLoad[T any](r io.Reader, opts ...Option) T {
+	config, err := Load[T](r, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+// MustLoadFile is like LoadFile but panics if the file cannot be parsed.
+// It is intended for use in main packages and tests.
+func MustLoadFile[T any](filename string, opts ...Option) T {
+	config, err := LoadFile[T](filename, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
@@ -0,0 +1,147 @@
+package simpleini
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	iniContent := `
+app_name = MyApp
+version = 1.0.0
+
+[database]
+host = db.local
+port = 5432
+`
+
+	config, err := Load[Config](strings.NewReader(iniContent))
+	if err != nil {
+		t.Fatalf("Failed to load INI: %v", err)
+	}
+
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be 'MyApp', got '%s'", config.AppName)
+	}
+	if config.Version == nil || *config.Version != "1.0.0" {
+		t.Errorf("Expected version to be '1.0.0', got %v", config.Version)
+	}
+	if config.Database.Port != 5432 {
+		t.Errorf("Expected database port to be 5432, got %d", config.Database.Port)
+	}
+}
+
+func TestLoad_DefaultValues(t *testing.T) {
+	config, err := Load[DefaultConfig](strings.NewReader("name = custom_name\n"))
+	if err != nil {
+		t.Fatalf("Failed to load INI: %v", err)
+	}
+
+	if config.Name != "custom_name" {
+		t.Errorf("Expected name to be 'custom_name', got '%s'", config.Name)
+	}
+	if config.Age == nil || *config.Age != 25 {
+		t.Errorf("Expected age to default to 25, got %v", config.Age)
+	}
+	if config.Score != 75.5 {
+		t.Errorf("Expected score to default to 75.5, got %f", config.Score)
+	}
+}
+
+func TestLoad_WithDelimiter(t *testing.T) {
+	config, err := Load[Config](strings.NewReader("app_name: MyApp\n"), WithDelimiter(":"))
+	if err != nil {
+		t.Fatalf("Failed to load INI with custom delimiter: %v", err)
+	}
+
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be 'MyApp', got '%s'", config.AppName)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	iniContent := `
+[server]
+port = not_a_uint
+timeout = not_a_float
+`
+
+	_, err := Load[Config](strings.NewReader(iniContent))
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "error at line 3") || !strings.Contains(err.Error(), "error at line 4") {
+		t.Errorf("Expected both errors to be reported, got %v", err)
+	}
+}
+
+func TestLoad_NonStructType(t *testing.T) {
+	_, err := Load[int](strings.NewReader("app_name = MyApp\n"))
+	if err == nil || !strings.Contains(err.Error(), "type parameter must be a struct, got int") {
+		t.Fatalf("Expected error for non-struct type, got %v", err)
+	}
+
+	_, err = Load[*Config](strings.NewReader("app_name = MyApp\n"))
+	if err == nil || !strings.Contains(err.Error(), "type parameter must be a struct, got *simpleini.Config") {
+		t.Fatalf("Expected error for pointer type, got %v", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "server.ini"), []byte("[server]\nhost = localhost\n"), 0644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+	mainFile := filepath.Join(dir, "main.ini")
+	if err := os.WriteFile(mainFile, []byte("app_name = MyApp\n!include server.ini\n"), 0644); err != nil {
+		t.Fatalf("Failed to write main file: %v", err)
+	}
+
+	config, err := LoadFile[Config](mainFile)
+	if err != nil {
+		t.Fatalf("Failed to load INI file: %v", err)
+	}
+
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be 'MyApp', got '%s'", config.AppName)
+	}
+	if config.Server.Host != "localhost" {
+		t.Errorf("Expected server host to be 'localhost', got '%s'", config.Server.Host)
+	}
+}
+
+func TestLoadFile_NotFound(t *testing.T) {
+	_, err := LoadFile[Config](filepath.Join(t.TempDir(), "missing.ini"))
+	if err == nil || !strings.Contains(err.Error(), "failed to open file") {
+		t.Fatalf("Expected error for missing file, got %v", err)
+	}
+}
+
+func TestMustLoad(t *testing.T) {
+	config := MustLoad[Config](strings.NewReader("app_name = MyApp\n"))
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be 'MyApp', got '%s'", config.AppName)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected MustLoad to panic on invalid content")
+		}
+	}()
+	MustLoad[Config](strings.NewReader("app_name MyApp\n"))
+}
+
+func TestMustLoadFile_Panics(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("Expected MustLoadFile to panic for non-struct type")
+		}
+		if err, ok := r.(error); !ok || !strings.Contains(err.Error(), "must be a struct") {
+			t.Errorf("Unexpected panic value: %v", r)
+		}
+	}()
+	MustLoadFile[string]("config.ini")
+}
@@ -0,0 +1,130 @@
+package simpleini
+
+import "os"
+
+// options holds the settings that control how an INI file is decoded.
+type options struct {
+	delimiter      string
+	inlineComments []string
+	continuation   Continuation
+	sliceSeparator string
+	arrayKeys      bool
+
+	subsections       bool
+	looseNames        bool
+	valuelessKeys     bool
+	extendedBooleans  bool
+	includeSection    bool
+	indentKeys        bool
+	caseMode          CaseMode
+	appendSlices      bool
+	rawValues         bool
+	expandEnv         bool
+	tightDelimiter    bool
+	backslashJoin     string
+	nameFunc          func(fieldName string) string
+	freeformSections  bool
+	localizedKeys     bool
+	terminatedLists   bool
+	interpolation     Interpolation
+	lookupEnv         func(name string) (string, bool)
+	resolvers         map[string]Resolver
+	revealSecrets     bool
+	decrypter         Decrypter
+	encrypter         Encrypter
+	profile           string
+	includes          bool
+	includeRoots      []string
+	maxIncludeDepth   int
+	limits            Limits
+	encoding          Encoding
+	duplicateKeys     DuplicatePolicy
+	duplicateSections DuplicatePolicy
+	warningHandler    func(Warning)
+}
+
+// Option configures the decoder used by Parse, Load and LoadFile.
+type Option func(*options)
+
+// Continuation is a set of flags selecting the syntaxes that let a value span multiple lines.
+type Continuation int
+
+const (
+	// ContinuationIndent continues a value on lines that start with a space or tab.
+	ContinuationIndent Continuation = 1 << iota
+	// ContinuationBackslash continues a value on the next line when it ends with a backslash.
+	ContinuationBackslash
+	// ContinuationHeredoc allows blocks delimited by """ or '''. Lines inside a block are
+	// kept verbatim. Environment variables are expanded in """ blocks but not in ''' blocks.
+	ContinuationHeredoc
+)
+
+// newOptions returns the options with defaults applied, followed by the given overrides.
+func newOptions(opts []Option) options {
+	o := options{
+		delimiter:       delimiter,
+		continuation:    ContinuationIndent,
+		expandEnv:       true,
+		lookupEnv:       os.LookupEnv,
+		backslashJoin:   "\n",
+		nameFunc:        pascalToSnake,
+		includes:        true,
+		maxIncludeDepth: defaultMaxIncludeDepth,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDelimiter sets the delimiter for key-value pairs, overriding SetDelimiter for a single decode.
+func WithDelimiter(d string) Option {
+	return func(o *options) {
+		o.delimiter = d
+	}
+}
+
+// WithInlineComments enables comments after values. A comment starts at any of the
+// given prefixes when it is preceded by whitespace and not inside a quoted value.
+// If no prefixes are given, both ';' and '#' are recognised.
+func WithInlineComments(prefixes ...string) Option {
+	return func(o *options) {
+		if len(prefixes) == 0 {
+			prefixes = []string{";", "#"}
+		}
+		o.inlineComments = prefixes
+	}
+}
+
+// WithContinuation selects the syntaxes that let a value span multiple lines.
+// The default is ContinuationIndent.
+func WithContinuation(c Continuation) Option {
+	return func(o *options) {
+		o.continuation = c
+	}
+}
+
+// WithSliceSeparator sets the separator used to split values into slice fields
+// that have no sep tag. Newlines always separate elements as well.
+func WithSliceSeparator(sep string) Option {
+	return func(o *options) {
+		o.sliceSeparator = sep
+	}
+}
+
+// WithEnvExpansion enables or disables the expansion of environment variables
+// in values. Expansion is enabled by default, and can be disabled for a single
+// field with the expand:"false" tag.
+func WithEnvExpansion(enabled bool) Option {
+	return func(o *options) {
+		o.expandEnv = enabled
+	}
+}
+
+// WithEnvLookup sets the function used to look up environment variables instead
+// of os.LookupEnv, for example to inject variables in tests.
+func WithEnvLookup(lookup func(name string) (string, bool)) Option {
+	return func(o *options) {
+		o.lookupEnv = lookup
+	}
+}
@@ -1,7 +1,6 @@
 package simpleini
 
 import (
-	"bufio"
 	"encoding"
 	"errors"
 	"fmt"
@@ -34,7 +33,7 @@ func getFieldMap(t reflect.Type) (map[string]reflect.StructField, error) {
 	fieldMap := make(map[string]reflect.StructField)
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		tagName := field.Tag.Get("ini")
+		tagName := fieldTagName(field)
 		if tagName == "" {
 			tagName = snakeToPascal(field.Name)
 		}
@@ -138,8 +137,100 @@ func setFieldValue(fieldValue reflect.Value, value string) error {
 	return nil
 }
 
+// fieldSeparator returns the separator used to split a value into the elements
+// of a slice field, or an empty string if the field is not split. The sep tag
+// takes precedence over the given default. Slices that implement
+// encoding.TextUnmarshaler are never split.
+func fieldSeparator(field reflect.StructField, defaultSep string) string {
+	t := indirectType(field.Type)
+	if t.Kind() != reflect.Slice || reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return ""
+	}
+	if sep, ok := field.Tag.Lookup("sep"); ok {
+		return sep
+	}
+	return defaultSep
+}
+
+// isAppendable reports whether a field of the type collects repeated keys as
+// slice elements. Slices that implement encoding.TextUnmarshaler do not.
+func isAppendable(t reflect.Type) bool {
+	t = indirectType(t)
+	return t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+}
+
+// appendSliceValue appends the value as a new element of the slice. An empty
+// value clears the slice instead.
+func appendSliceValue(fieldValue reflect.Value, value string) error {
+	fieldValue = initializePointer(fieldValue, true)
+	if !fieldValue.CanSet() {
+		return fmt.Errorf("cannot set unexported field")
+	}
+	if value == "" {
+		fieldValue.Set(reflect.Zero(fieldValue.Type()))
+		return nil
+	}
+	element := reflect.New(fieldValue.Type().Elem()).Elem()
+	if err := setFieldValue(element, value); err != nil {
+		return err
+	}
+	fieldValue.Set(reflect.Append(fieldValue, element))
+	return nil
+}
+
+// setListValue splits the value on the separator and sets each element of the slice.
+func setListValue(fieldValue reflect.Value, value, sep string) error {
+	elements, err := splitList(value, sep)
+	if err != nil {
+		return err
+	}
+
+	fieldValue = initializePointer(fieldValue, true)
+	if !fieldValue.CanSet() {
+		return fmt.Errorf("cannot set unexported field")
+	}
+	slice := reflect.MakeSlice(fieldValue.Type(), len(elements), len(elements))
+	for i, element := range elements {
+		if err := setFieldValue(slice.Index(i), element); err != nil {
+			return err
+		}
+	}
+	fieldValue.Set(slice)
+	return nil
+}
+
+// maxIndexGap is the number of elements a key[N] index may add beyond the end
+// of a slice.
+const maxIndexGap = 1024
+
+// decoder holds the state shared across a single decode, including any included files.
+type decoder struct {
+	opts          options
+	config        interface{}
+	including     map[string]bool // files being read, to detect circular includes
+	limitExceeded bool            // stops reading once a limit is exceeded
+	keyCount      int             // number of key assignments read, for Limits.MaxKeys
+	arrayLengths  map[string]int  // number of elements set in each array field by key[]
+	assignments   []assignment    // values in the order they were read
+	sections      map[string]bool
+	sectionOrder  []string               // sections in the order they were declared
+	bases         map[string]sectionBase // base section of each inheriting section
+}
+
+// newDecoder returns a decoder that populates config using the given options.
+func newDecoder(config interface{}, opts ...Option) *decoder {
+	return &decoder{
+		opts:         newOptions(opts),
+		config:       config,
+		including:    make(map[string]bool),
+		arrayLengths: make(map[string]int),
+		sections:     make(map[string]bool),
+		bases:        make(map[string]sectionBase),
+	}
+}
+
 // setDefaultValues sets the default values for all fields in the struct.
-func setDefaultValues(v reflect.Value) error {
+func (d *decoder) setDefaultValues(v reflect.Value) error {
 	fieldMap, err := getFieldMap(v.Type())
 	if err != nil {
 		return err
@@ -149,15 +240,21 @@ func setDefaultValues(v reflect.Value) error {
 		fieldValue := v.FieldByName(field.Name)
 		defaultValue := field.Tag.Get("default")
 		if defaultValue != "" {
-			fieldValue = initializePointer(fieldValue, true)
-			if err := setFieldValue(fieldValue, defaultValue); err != nil {
-				return err
+			if sep := fieldSeparator(field, d.opts.sliceSeparator); sep != "" {
+				if err := setListValue(fieldValue, defaultValue, sep); err != nil {
+					return err
+				}
+			} else {
+				fieldValue = initializePointer(fieldValue, true)
+				if err := setFieldValue(fieldValue, defaultValue); err != nil {
+					return err
+				}
 			}
 		}
 
 		// Recursively set default values for nested structs
 		if fieldValue.Kind() == reflect.Struct {
-			if err := setDefaultValues(fieldValue); err != nil {
+			if err := d.setDefaultValues(fieldValue); err != nil {
 				return err
 			}
 		} else if fieldValue.Kind() == reflect.Ptr && fieldValue.Type().Elem().Kind() == reflect.Struct {
@@ -169,7 +266,7 @@ func setDefaultValues(v reflect.Value) error {
 			for _, embeddedField := range embeddedFieldMap {
 				if embeddedField.Tag.Get("default") != "" {
 					fieldValue = initializePointer(fieldValue, true)
-					if err := setDefaultValues(fieldValue); err != nil {
+					if err := d.setDefaultValues(fieldValue); err != nil {
 						return err
 					}
 					break
@@ -180,163 +277,746 @@ func setDefaultValues(v reflect.Value) error {
 	return nil
 }
 
-// setStructValue sets the value of a field in the struct.
-func setStructValue(v reflect.Value, key, value string) error {
+// findField returns the struct field that matches the key.
+func (d *decoder) findField(v reflect.Value, key string) (reflect.StructField, error) {
 	fieldMap, err := getFieldMap(v.Type())
 	if err != nil {
-		return err
+		return reflect.StructField{}, err
 	}
 
 	// Find the field by key
+	if d.opts.caseMode == CaseSensitive {
+		return d.findFieldExact(fieldMap, key)
+	}
 	field, ok := fieldMap[key]
 	if !ok {
 		field, ok = fieldMap[snakeToPascal(key)]
-		if !ok {
-			return fmt.Errorf("no matching field found for key '%s'", key)
+	}
+	if !ok && d.opts.caseMode == CasePreserving {
+		for name, f := range fieldMap {
+			if strings.EqualFold(name, key) || strings.EqualFold(name, snakeToPascal(key)) {
+				field, ok = f, true
+				break
+			}
+		}
+	}
+	if !ok {
+		field, ok = d.findAlias(fieldMap, key)
+	}
+	if !ok && d.opts.looseNames {
+		// Match names such as defaultbranch to DefaultBranch or default_branch
+		for name, f := range fieldMap {
+			if looseEqual(name, key) {
+				field, ok = f, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return reflect.StructField{}, fmt.Errorf("no matching field found for key '%s'", key)
+	}
+	return field, nil
+}
+
+// findFieldExact returns the struct field whose INI name or alias is the key,
+// matching case exactly.
+func (d *decoder) findFieldExact(fieldMap map[string]reflect.StructField, key string) (reflect.StructField, error) {
+	for _, field := range fieldMap {
+		if d.fieldName(field) == key {
+			return field, nil
 		}
 	}
+	if field, ok := d.findAlias(fieldMap, key); ok {
+		return field, nil
+	}
+	return reflect.StructField{}, fmt.Errorf("no matching field found for key '%s'", key)
+}
+
+// fieldName returns the INI name of a field, from its ini tag or its name.
+func (d *decoder) fieldName(field reflect.StructField) string {
+	if name := fieldTagName(field); name != "" {
+		return name
+	}
+	return d.opts.nameFunc(field.Name)
+}
+
+// setStructValue sets the value of a field in the struct.
+func (d *decoder) setStructValue(v reflect.Value, key, value string) error {
+	field, err := d.findField(v, key)
+	if err != nil {
+		return err
+	}
 
 	fieldValue := v.FieldByName(field.Name)
+	if d.opts.extendedBooleans && indirectType(field.Type).Kind() == reflect.Bool {
+		value = normalizeBool(value)
+	}
+	if sep := fieldSeparator(field, d.opts.sliceSeparator); sep != "" {
+		return setListValue(fieldValue, value, sep)
+	}
+	if d.opts.appendSlices && isAppendable(field.Type) {
+		return appendSliceValue(fieldValue, value)
+	}
+	if d.opts.localizedKeys && isLocalizedMap(field.Type) {
+		return d.setIndexedStructValue(v, "", key, "", value)
+	}
 	fieldValue = initializePointer(fieldValue, value != "")
 	return setFieldValue(fieldValue, value)
 }
 
-// setConfigValue sets the value of a field in the config struct.
-func setConfigValue(config interface{}, section, key, value string) error {
+// withSection calls fn with the struct for the section, initializing pointers
+// and map entries along the way. A map field with string keys and struct values
+// uses the next part of the section name as the map key.
+func (d *decoder) withSection(section string, fn func(v reflect.Value) error) error {
 	// Check if the config is a pointer to a struct
-	v := reflect.ValueOf(config)
+	v := reflect.ValueOf(d.config)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 		return errors.New("configuration must be a pointer to a struct")
 	}
 	v = v.Elem()
 
-	// If no section is specified, set the value in the root struct
+	// If no section is specified, use the root struct
 	if section == "" {
-		return setStructValue(v, key, value)
+		return fn(v)
 	}
+	return d.walkSection(v, splitSection(section), section, fn)
+}
 
-	// Traverse the struct fields to find the section
-	sectionParts := strings.Split(section, ".")
-	for _, part := range sectionParts {
-		part = strings.ToLower(part)
-		// Find the field by tag or converted name
-		field := v.FieldByNameFunc(func(name string) bool {
-			field, ok := v.Type().FieldByName(name)
-			return ok && (strings.EqualFold(field.Tag.Get("ini"), part) || strings.EqualFold(snakeToPascal(part), name))
-		})
+// walkSection traverses the struct fields for the remaining parts of the section name.
+func (d *decoder) walkSection(v reflect.Value, parts []string, section string, fn func(v reflect.Value) error) error {
+	if len(parts) == 0 {
+		return fn(v)
+	}
 
-		// If the field is not found, return an error
-		if !field.IsValid() {
-			return fmt.Errorf("no matching field found for section '%s'", section)
+	// Find the field by tag or converted name
+	sf, ok := d.sectionField(v.Type(), parts[0])
+	if !ok {
+		return fmt.Errorf("no matching field found for section '%s'", section)
+	}
+	field := v.FieldByIndex(sf.Index)
+
+	// Initialize the pointer if necessary
+	field = initializePointer(field, true)
+
+	// Use the next part as the key of a map of sections
+	if isSectionMap(field.Type()) && len(parts) > 1 {
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		key := reflect.ValueOf(parts[1]).Convert(field.Type().Key())
+		entry := field.MapIndex(key)
+		elemType := field.Type().Elem()
+		if elemType.Kind() == reflect.Ptr {
+			if !entry.IsValid() || entry.IsNil() {
+				entry = reflect.New(elemType.Elem())
+				if err := d.setDefaultValues(entry.Elem()); err != nil {
+					return err
+				}
+				field.SetMapIndex(key, entry)
+			}
+			return d.walkSection(entry.Elem(), parts[2:], section, fn)
+		}
+
+		// Map values are not addressable, so update a copy and store it back
+		elem := reflect.New(elemType).Elem()
+		if entry.IsValid() {
+			elem.Set(entry)
+		} else if err := d.setDefaultValues(elem); err != nil {
+			return err
 		}
+		err := d.walkSection(elem, parts[2:], section, fn)
+		field.SetMapIndex(key, elem)
+		return err
+	}
 
-		// Initialize the pointer if necessary
-		field = initializePointer(field, true)
+	// Check if the field is a struct
+	if field.Kind() != reflect.Struct {
+		return fmt.Errorf("field for section '%s' is not a struct", section)
+	}
+	return d.walkSection(field, parts[1:], section, fn)
+}
+
+// sectionField returns the field of the struct type that a part of a section
+// name refers to.
+func (d *decoder) sectionField(t reflect.Type, part string) (reflect.StructField, bool) {
+	lower := strings.ToLower(part)
+	return t.FieldByNameFunc(func(name string) bool {
+		field, ok := t.FieldByName(name)
+		if d.opts.caseMode == CaseSensitive {
+			return ok && d.fieldName(field) == part
+		}
+		if ok && d.opts.looseNames && looseEqual(lower, name) {
+			return true
+		}
+		return ok && (strings.EqualFold(fieldTagName(field), lower) || strings.EqualFold(snakeToPascal(lower), name))
+	})
+}
 
-		// Check if the field is a struct
-		if field.Kind() != reflect.Struct {
-			return fmt.Errorf("field for section '%s' is not a struct", section)
+// sectionType returns the struct type of a section. It follows the same fields
+// as walkSection, but only through their types, so nothing is allocated in the
+// config.
+func (d *decoder) sectionType(section string) (reflect.Type, bool) {
+	t := reflect.TypeOf(d.config)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	t = t.Elem()
+	if section == "" {
+		return t, true
+	}
+	for parts := splitSection(section); len(parts) > 0; {
+		field, ok := d.sectionField(t, parts[0])
+		if !ok {
+			return nil, false
 		}
-		v = field
+		t = indirectType(field.Type)
+		if isSectionMap(t) && len(parts) > 1 {
+			t = indirectType(t.Elem())
+			parts = parts[2:]
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		parts = parts[1:]
 	}
+	return t, true
+}
 
-	return setStructValue(v, key, value)
+// setConfigValue sets the value of a field in the config struct.
+func (d *decoder) setConfigValue(section, key, value string) error {
+	return d.withSection(section, func(v reflect.Value) error {
+		return d.setStructValue(v, key, value)
+	})
 }
 
-// processMultilineValue processes and sets a multiline value.
-func processMultilineValue(config interface{}, section, key, value string, lineNumber int) error {
-	value = substituteEnvVars(value)
-	if err := setConfigValue(config, section, key, value); err != nil {
-		return fmt.Errorf("error at line %d: %w", lineNumber, err)
+// setIndexedValue sets an element of a slice, array or map field from a PHP-style
+// array key. An empty index appends to a slice or array, a numeric index sets
+// that element, and a map field uses the index as the map key.
+func (d *decoder) setIndexedValue(section, key, index, value string) error {
+	return d.withSection(section, func(v reflect.Value) error {
+		return d.setIndexedStructValue(v, section, key, index, value)
+	})
+}
+
+// setIndexedStructValue sets an element of a slice, array or map field in the struct.
+func (d *decoder) setIndexedStructValue(v reflect.Value, section, key, index, value string) error {
+	field, err := d.findField(v, key)
+	if err != nil {
+		return err
+	}
+
+	fieldValue := initializePointer(v.FieldByName(field.Name), true)
+	if !fieldValue.CanSet() {
+		return fmt.Errorf("cannot set unexported field")
+	}
+
+	if fieldValue.Type() == localizedStringType {
+		fieldValue.Addr().Interface().(*LocalizedString).Set(index, value)
+		return nil
+	}
+
+	switch fieldValue.Kind() {
+	case reflect.Slice:
+		n := fieldValue.Len()
+		if index != "" {
+			if n, err = strconv.Atoi(index); err != nil || n < 0 {
+				return fmt.Errorf("invalid index '%s' for slice field '%s'", index, key)
+			}
+		}
+		// Grow the slice by a bounded number of elements, so that a huge index
+		// is an error instead of an allocation
+		if n > fieldValue.Len()+maxIndexGap {
+			return fmt.Errorf("index %d out of range for slice field '%s' of length %d", n, key, fieldValue.Len())
+		}
+		if err := d.opts.limits.checkSliceLength(n+1, key); err != nil {
+			return err
+		}
+		if n >= fieldValue.Len() {
+			grown := reflect.MakeSlice(fieldValue.Type(), n+1, n+1)
+			reflect.Copy(grown, fieldValue)
+			fieldValue.Set(grown)
+		}
+		return setFieldValue(fieldValue.Index(n), value)
+	case reflect.Array:
+		path := section + "." + key
+		n := d.arrayLengths[path]
+		if index != "" {
+			if n, err = strconv.Atoi(index); err != nil || n < 0 {
+				return fmt.Errorf("invalid index '%s' for array field '%s'", index, key)
+			}
+		}
+		if n >= fieldValue.Len() {
+			return fmt.Errorf("index %d out of range for array field '%s' of length %d", n, key, fieldValue.Len())
+		}
+		d.arrayLengths[path] = max(d.arrayLengths[path], n+1)
+		return setFieldValue(fieldValue.Index(n), value)
+	case reflect.Map:
+		if index == "" && !d.opts.localizedKeys {
+			return fmt.Errorf("map field '%s' requires an index", key)
+		}
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.MakeMap(fieldValue.Type()))
+		}
+		mapKey := reflect.New(fieldValue.Type().Key()).Elem()
+		if err := setFieldValue(mapKey, index); err != nil {
+			return err
+		}
+		mapValue := reflect.New(fieldValue.Type().Elem()).Elem()
+		if err := setFieldValue(mapValue, value); err != nil {
+			return err
+		}
+		fieldValue.SetMapIndex(mapKey, mapValue)
+		return nil
+	default:
+		return fmt.Errorf("field for key '%s[%s]' is not a slice, array or map", key, index)
+	}
+}
+
+// lookupField returns the field that matches the key in the section, and false
+// if it cannot be found. Unlike withSection, it leaves the config untouched.
+func (d *decoder) lookupField(section, key string) (reflect.StructField, bool) {
+	t, ok := d.sectionType(section)
+	if !ok {
+		return reflect.StructField{}, false
+	}
+	field, err := d.findField(reflect.Zero(t), key)
+	return field, err == nil
+}
+
+// lineState tracks the position within a single file while its lines are processed.
+type lineState struct {
+	header      string // section named by the last section header
+	section     string // section of the current key, which a dotted key extends
+	key         string
+	value       string
+	keyLine     int
+	index       string // index of a PHP-style array key such as key[] or key[0]
+	indexed     bool
+	sep         string // separator when the key is split into a slice, which keeps quotes for splitList
+	inMultiline bool   // the value of the current key may continue on indented lines
+	continued   bool   // the previous line ended with a backslash
+	heredoc     string // closing delimiter while inside a triple-quoted block
+	profile     string // active profile qualifying the section
+	inactive    bool   // the keys of the section are ignored, as for another profile
+	noExpand    bool   // the field of the key has expand:"false"
+	include     string // file named by an include.path key, followed after the line
+
+	sectionLines map[seenName]int // line of each section header, to detect duplicates
+	keyLines     map[seenName]int // line of each key, to detect duplicates
+}
+
+// setValue sets the value of the current key, reporting errors at the given line.
+// Values with references to other keys are deferred until the whole file has
+// been read.
+func (d *decoder) setValue(st *lineState, value string, lineNumber int) error {
+	if st.inactive {
+		return nil
+	}
+	if err := d.checkKeyCount(lineNumber); err != nil {
+		return err
+	}
+	d.keyCount++
+	skip, appended, err := d.checkDuplicateKey(st, lineNumber)
+	if skip {
+		return err
+	}
+	a := assignment{
+		section:  st.section,
+		key:      st.key,
+		index:    st.index,
+		indexed:  st.indexed,
+		value:    value,
+		raw:      st.heredoc == "'''",
+		expand:   d.opts.expandEnv && !st.noExpand,
+		override: st.profile != "",
+		appended: appended,
+		line:     lineNumber,
+	}
+	switch {
+	case a.override:
+		a.deferred = true
+	case a.raw:
+	case d.opts.interpolation != InterpolationNone || containsReference(value):
+		a.deferred = true
+	default:
+		expanded, err := d.expandEnv(st, value)
+		if err != nil {
+			return fmt.Errorf("error at line %d: %w", lineNumber, err)
+		}
+		a.value = expanded
+	}
+	d.assignments = append(d.assignments, a)
+	if a.deferred {
+		return nil
+	}
+	return d.assign(a)
+}
+
+// assign sets the value of an assignment on the config, decrypting enc:v1: values.
+func (d *decoder) assign(a assignment) error {
+	// With interpolation, the keys of the [DEFAULT] section are only referenced
+	if d.opts.interpolation != InterpolationNone && d.foldName(a.section) == defaultSection {
+		return nil
+	}
+	var err error
+	if a.value, err = decryptValue(d.opts.decrypter, a.value); err != nil {
+		return fmt.Errorf("error at %s: %w", a.location(), err)
+	}
+	if err := d.opts.limits.checkValue(a.value); err != nil {
+		return fmt.Errorf("error at %s: %w", a.location(), err)
+	}
+	switch {
+	case a.indexed:
+		err = d.setIndexedValue(a.section, a.key, a.index, a.value)
+	case a.appended:
+		err = d.appendConfigValue(a.section, a.key, a.value)
+	default:
+		err = d.setConfigValue(a.section, a.key, a.value)
+	}
+	if err != nil {
+		return fmt.Errorf("error at %s: %w", a.location(), err)
 	}
 	return nil
 }
 
-// processLine processes a single line from the INI file.
-func processLine(line string, config interface{}, currentSection *string, currentKey *string, currentValue *string, inMultiline *bool, lineNumber int) error {
-	// Check for multiline continuation
-	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
-		*inMultiline = true
-		*currentValue += "\n" + strings.TrimSpace(line)
+// expandEnv replaces environment variable references in the value, unless
+// expansion is disabled for the decoder or for the field of the current key.
+// $$ is a literal $ either way, as it is in values with key references.
+func (d *decoder) expandEnv(st *lineState, value string) (string, error) {
+	if !d.opts.expandEnv || st.noExpand {
+		return strings.ReplaceAll(value, "$$", "$"), nil
+	}
+	return substituteEnvVars(value, d.lookupEnv)
+}
+
+// lookupEnv resolves a ${scheme:ref} reference with a registered resolver, or
+// looks up an environment variable with the configured lookup function. A
+// scheme without a resolver is an error rather than an unset variable, so that
+// a missing resolver or a misspelled scheme does not leave a secret empty.
+func (d *decoder) lookupEnv(name string) (string, bool, error) {
+	if value, ok, err := d.resolve(name); ok {
+		return value, true, err
+	}
+	if scheme, _, ok := strings.Cut(name, ":"); ok {
+		return "", false, fmt.Errorf("no resolver registered for scheme '%s' in '${%s}'", scheme, name)
+	}
+	value, ok := d.opts.lookupEnv(name)
+	return value, ok, nil
+}
+
+// processMultilineValue sets the value of the current key once it is complete,
+// including any indented continuation lines.
+func (d *decoder) processMultilineValue(st *lineState) error {
+	st.inMultiline = false
+	return d.setValue(st, st.value, st.keyLine)
+}
+
+// processHeredocLine adds a line to a triple-quoted block, setting the value once
+// the closing delimiter is reached. Lines are kept verbatim, and environment
+// variables are only expanded in double-quoted blocks.
+func (d *decoder) processHeredocLine(line string, st *lineState) error {
+	trimmed := strings.TrimRight(line, " \t")
+	closed := strings.HasSuffix(trimmed, st.heredoc)
+	if closed {
+		line = strings.TrimSuffix(trimmed, st.heredoc)
+	}
+	if !closed || line != "" {
+		if st.inMultiline {
+			st.value += "\n"
+		}
+		st.value += line
+		st.inMultiline = true
+	}
+	if !closed {
 		return nil
 	}
 
-	// Process the previous multiline value
-	if *inMultiline {
-		if err := processMultilineValue(config, *currentSection, *currentKey, *currentValue, lineNumber); err != nil {
-			return err
+	err := d.setValue(st, st.value, st.keyLine)
+	st.heredoc, st.inMultiline = "", false
+	return err
+}
+
+// processContinuedLine adds a line to a value whose previous line ended with a
+// backslash, setting the value once a line without a backslash is reached.
+func (d *decoder) processContinuedLine(line string, st *lineState, lineNumber int) error {
+	line, _ = splitInlineComment(strings.TrimSpace(line), d.opts.inlineComments)
+	line, st.continued = cutContinuation(line)
+	line, err := d.unquoteValue(st, line)
+	if err != nil {
+		return fmt.Errorf("error at line %d: %w", lineNumber, err)
+	}
+	st.value += d.opts.backslashJoin + line
+	if st.continued {
+		return nil
+	}
+	return d.setValue(st, st.value, st.keyLine)
+}
+
+// unquoteValue unquotes a line of the current value, unless values are raw or
+// the value is split into a slice, in which case the quotes are kept so each
+// element can be unquoted.
+func (d *decoder) unquoteValue(st *lineState, line string) (string, error) {
+	if st.sep != "" || d.opts.rawValues {
+		return line, nil
+	}
+	return unquoteValue(line)
+}
+
+// cutContinuation removes a trailing backslash and reports whether it was present.
+func cutContinuation(line string) (string, bool) {
+	if !strings.HasSuffix(line, "\\") {
+		return line, false
+	}
+	return strings.TrimRight(strings.TrimSuffix(line, "\\"), " \t"), true
+}
+
+// normalizeName lowercases a section or key name, unless its case is
+// preserved. Dashes are replaced with
+// underscores when loose names are enabled, so they match snake_case fields.
+func (d *decoder) normalizeName(name string) string {
+	if d.opts.caseMode == CaseInsensitive {
+		name = strings.ToLower(name)
+	}
+	if d.opts.looseNames {
+		name = strings.ReplaceAll(name, "-", "_")
+	}
+	return name
+}
+
+// cutDottedKey splits a dotted key such as logging.level into the section it
+// addresses, relative to the section of the header, and the key. It reports
+// false if the key is invalid. Dotted keys cannot extend a quoted subsection.
+func cutDottedKey(header, key string) (string, string, bool) {
+	if !strings.Contains(key, ".") || strings.Contains(header, `"`) {
+		return header, key, false
+	}
+	for _, part := range strings.Split(key, ".") {
+		if !isValidKey(part) {
+			return header, key, false
+		}
+	}
+	prefix, name := splitKeyPath(key)
+	if header == "" {
+		return prefix, name, true
+	}
+	return header + "." + prefix, name, true
+}
+
+// parseSectionHeader returns the normalized section name for the text between
+// the brackets of a section header, and false if the name is invalid. When
+// subsections are enabled, a quoted subsection such as remote "origin" keeps
+// its case and may contain any character.
+func (d *decoder) parseSectionHeader(header string) (string, bool) {
+	name, subsection, hasSubsection := header, "", false
+	if i := strings.IndexByte(header, '"'); i >= 0 && d.opts.subsections {
+		quoted := strings.TrimSpace(header[i:])
+		if !isQuoted(quoted) {
+			return header, false
+		}
+		var err error
+		if subsection, err = unquoteValue(quoted); err != nil {
+			return header, false
+		}
+		name, hasSubsection = strings.TrimSpace(header[:i]), true
+	}
+
+	name = d.normalizeName(name)
+	if d.opts.freeformSections {
+		if !isValidFreeformSection(name) {
+			return name, false
 		}
-		*inMultiline = false
+	} else if !isValidSection(name) {
+		return name, false
+	}
+	if hasSubsection {
+		return name + " " + quoteString(subsection), true
+	}
+	return name, true
+}
+
+// isIndentedContinuation checks if the line continues the value of the previous
+// key because it is indented.
+func (d *decoder) isIndentedContinuation(line string) bool {
+	return d.opts.continuation&ContinuationIndent != 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"))
+}
+
+// processLine processes a single line from the INI file. The value of the
+// previous key must have been set unless the line continues it.
+func (d *decoder) processLine(line string, st *lineState, lineNumber int) error {
+	if st.heredoc != "" {
+		return d.processHeredocLine(line, st)
+	}
+	if st.continued {
+		return d.processContinuedLine(line, st, lineNumber)
+	}
+
+	// Check for multiline continuation
+	if d.isIndentedContinuation(line) {
+		line, _ = splitInlineComment(strings.TrimSpace(line), d.opts.inlineComments)
+		line, err := d.unquoteValue(st, line)
+		if err != nil {
+			return fmt.Errorf("error at line %d: %w", lineNumber, err)
+		}
+		st.inMultiline = true
+		st.value += "\n" + line
+		return nil
 	}
 
 	line = strings.TrimSpace(line)
 	if len(line) == 0 || line[0] == ';' || line[0] == '#' {
 		return nil
 	}
+	line, _ = splitInlineComment(line, d.opts.inlineComments)
 
 	// Check if the line is a section header
 	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-		section := strings.ToLower(line[1 : len(line)-1])
-		if !isValidSection(section) {
-			return fmt.Errorf("invalid section name at line %d: %s", lineNumber, section)
+		header, base, inherits := cutSectionBase(line[1 : len(line)-1])
+		header, profile, qualified := cutProfile(header)
+		if qualified && !isValidProfile(profile) {
+			return fmt.Errorf("invalid profile name at line %d: %s", lineNumber, profile)
+		}
+		section := ""
+		if !qualified || header != "" {
+			var ok bool
+			if section, ok = d.parseSectionHeader(header); !ok {
+				return fmt.Errorf("invalid section name at line %d: %s", lineNumber, section)
+			}
+		}
+		st.header, st.section = section, section
+
+		// Skip the keys of sections qualified by another profile
+		st.profile, st.inactive = "", qualified && !d.isActiveProfile(profile)
+		if st.inactive {
+			return nil
+		}
+		if qualified {
+			st.profile = profile
+		}
+		skip, err := d.checkDuplicateSection(st, lineNumber)
+		if st.inactive = skip; skip {
+			return err
+		}
+		if section != "" && !d.sections[d.foldName(section)] {
+			if err := d.checkSectionCount(lineNumber); err != nil {
+				return err
+			}
+			d.sections[d.foldName(section)] = true
+			d.sectionOrder = append(d.sectionOrder, section)
+		}
+		if inherits {
+			return d.setSectionBase(section, base, lineNumber)
 		}
-		*currentSection = section
 	} else {
 		// Check if the line is a key-value pair
-		if !strings.Contains(line, delimiter) {
-			return fmt.Errorf("invalid line format at line %d: %s", lineNumber, line)
+		if !strings.Contains(line, d.opts.delimiter) {
+			if !d.opts.valuelessKeys {
+				return fmt.Errorf("invalid line format at line %d: %s", lineNumber, line)
+			}
+			line += d.opts.delimiter + "true"
 		}
 
 		// Split the line into key and value
-		keyValue := strings.SplitN(line, delimiter, 2)
-		key := strings.ToLower(strings.TrimSpace(keyValue[0]))
+		keyValue := strings.SplitN(line, d.opts.delimiter, 2)
+		key := strings.TrimSpace(keyValue[0])
+		st.index, st.indexed = "", false
+		if d.opts.arrayKeys {
+			key, st.index, st.indexed = splitArrayKey(key)
+		}
+		key = d.normalizeName(key)
+		st.section = st.header
+		if strings.Contains(key, ".") {
+			var ok bool
+			if st.section, key, ok = cutDottedKey(st.header, key); !ok {
+				return fmt.Errorf("invalid key name at line %d: %s", lineNumber, key)
+			}
+		}
 		if !isValidKey(key) {
 			return fmt.Errorf("invalid key name at line %d: %s", lineNumber, key)
 		}
-		*currentKey = key
-		*currentValue = strings.TrimSpace(keyValue[1])
-		*currentValue = substituteEnvVars(*currentValue)
+		st.key = key
+		st.keyLine = lineNumber
+		st.sep, st.noExpand = "", false
+		if !st.inactive {
+			if field, ok := d.lookupField(st.section, key); ok {
+				if !st.indexed {
+					st.sep = fieldSeparator(field, d.opts.sliceSeparator)
+				}
+				st.noExpand = field.Tag.Get("expand") == "false"
+				d.checkDeprecated(field, st, lineNumber)
+			}
+		}
+		value := strings.TrimSpace(keyValue[1])
 
-		// Use reflection to set the value in the config struct
-		if err := setConfigValue(config, *currentSection, *currentKey, *currentValue); err != nil {
+		// Check for the start of a triple-quoted block
+		if d.opts.continuation&ContinuationHeredoc != 0 && (strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''")) {
+			st.heredoc, st.value = value[:3], ""
+			if rest := strings.TrimLeft(value[3:], " \t"); rest != "" {
+				return d.processHeredocLine(rest, st)
+			}
+			return nil
+		}
+
+		// Check for a trailing backslash continuation
+		if d.opts.continuation&ContinuationBackslash != 0 {
+			value, st.continued = cutContinuation(value)
+		}
+
+		value, err := d.unquoteValue(st, value)
+		if err != nil {
 			return fmt.Errorf("error at line %d: %w", lineNumber, err)
 		}
-	}
+		st.value = value
+		if st.continued {
+			return nil
+		}
 
-	return nil
-}
+		// Defer an include.path key to parseReader, which follows includes
+		if d.opts.includeSection && st.section == "include" && key == "path" && !st.indexed {
+			if st.include, err = d.expandEnv(st, value); err != nil {
+				return fmt.Errorf("error at line %d: %w", lineNumber, err)
+			}
+			return nil
+		}
 
-// handleIncludeDirective processes an include directive.
-func handleIncludeDirective(line, basePath string, config interface{}, includedFiles map[string]bool, depth int) ([]error, bool) {
-	if strings.HasPrefix(line, "!include ") {
-		includeFile := strings.TrimSpace(line[len("!include "):])
-		if !filepath.IsAbs(includeFile) {
-			includeFile = filepath.Join(basePath, includeFile)
+		// An inherits key without a field names the base section
+		if key == inheritsKey && !st.indexed && st.section != "" && !st.inactive {
+			if _, ok := d.lookupField(st.section, key); !ok {
+				return d.setSectionBase(st.section, value, lineNumber)
+			}
+		}
+
+		// Wait for indented continuation lines, so the value is set once complete
+		if d.opts.continuation&ContinuationIndent != 0 {
+			st.inMultiline = true
+			return nil
+		}
+
+		// Use reflection to set the value in the config struct
+		if err := d.setValue(st, st.value, lineNumber); err != nil {
+			return err
 		}
-		includeErrors := parseFile(includeFile, config, includedFiles, depth)
-		return includeErrors, true
 	}
-	return nil, false
+
+	return nil
 }
 
 // parseReader parses the INI content from an io.Reader with support for include directives.
-func parseReader(reader io.Reader, config interface{}, includedFiles map[string]bool, depth int, basePath string) []error {
+func (d *decoder) parseReader(reader io.Reader, depth int, basePath string) []error {
 	var errors []error
-
-	// Set default values for all fields
-	if err := setDefaultValues(reflect.ValueOf(config).Elem()); err != nil {
-		errors = append(errors, err)
-	}
-
-	scanner := bufio.NewScanner(reader)
-	var currentSection, currentKey, currentValue string
-	var inMultiline bool
+	limits := d.opts.limits
+	scanner := newLineScanner(reader, limits, d.opts.encoding)
+	var st lineState
 	lineNumber := 0
 
-	// Read the file line by line
-	for scanner.Scan() {
+	// Read the file line by line until a limit is exceeded
+	for !d.limitExceeded && scanner.Scan() {
 		lineNumber++
 		line := scanner.Text()
+		if err := limits.checkLine(line, lineNumber); err != nil {
+			errors = append(errors, d.exceedLimit(err))
+			break
+		}
 
 		// Ensure the line is valid UTF-8
 		line, err := ensureValidUTF8(line)
@@ -345,23 +1025,59 @@ func parseReader(reader io.Reader, config interface{}, includedFiles map[string]
 			continue
 		}
 
+		// Set the value of the previous key, unless the line continues it
+		if st.inMultiline && st.heredoc == "" && !st.continued && !d.isIndentedContinuation(line) {
+			if err := d.processMultilineValue(&st); err != nil {
+				errors = append(errors, err)
+			}
+		}
+
 		// Handle include directive
-		if includeErrors, handled := handleIncludeDirective(line, basePath, config, includedFiles, depth); handled {
-			if includeErrors != nil {
-				errors = append(errors, includeErrors...)
+		if st.heredoc == "" && !st.continued {
+			if includeErrors, handled := d.handleIncludeDirective(line, basePath, depth); handled {
+				if includeErrors != nil {
+					errors = append(errors, includeErrors...)
+				}
+				continue
 			}
-			continue
 		}
 
 		// Process the line
-		if err := processLine(line, config, &currentSection, &currentKey, &currentValue, &inMultiline, lineNumber); err != nil {
+		if err := d.processLine(line, &st, lineNumber); err != nil {
 			errors = append(errors, err)
 		}
+		if st.heredoc != "" || st.continued || st.inMultiline {
+			if err := limits.checkValue(st.value); err != nil {
+				errors = append(errors, d.exceedLimit(fmt.Errorf("error at line %d: %w", st.keyLine, err)))
+			}
+		}
+
+		// Follow an include.path key
+		if st.include != "" {
+			includeFile := resolveIncludePath(st.include, basePath)
+			if err := d.checkInclude(includeFile); err != nil {
+				errors = append(errors, err)
+			} else {
+				errors = append(errors, d.parseFile(includeFile, depth+1)...)
+			}
+			st.include = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errors = append(errors, d.exceedLimit(limits.scanError(err, lineNumber)))
 	}
 
 	// Process any remaining multiline value
-	if inMultiline {
-		if err := processMultilineValue(config, currentSection, currentKey, currentValue, lineNumber); err != nil {
+	switch {
+	case d.limitExceeded:
+	case st.heredoc != "":
+		errors = append(errors, fmt.Errorf("unterminated multiline value starting at line %d", st.keyLine))
+	case st.continued:
+		if err := d.setValue(&st, st.value, st.keyLine); err != nil {
+			errors = append(errors, err)
+		}
+	case st.inMultiline:
+		if err := d.processMultilineValue(&st); err != nil {
 			errors = append(errors, err)
 		}
 	}
@@ -374,15 +1090,19 @@ func parseReader(reader io.Reader, config interface{}, includedFiles map[string]
 }
 
 // parseFile reads and parses an INI file with support for include directives.
-func parseFile(filename string, config interface{}, includedFiles map[string]bool, depth int) []error {
-	if depth > 10 {
+// The depth counts the includes leading to the file, so the file passed to
+// ParseFile, like the reader passed to Parse, is at depth 0.
+func (d *decoder) parseFile(filename string, depth int) []error {
+	if depth > d.opts.maxIncludeDepth {
 		return []error{fmt.Errorf("maximum include depth exceeded")}
 	}
 
-	if includedFiles[filename] {
+	// A file may be included more than once, but not while it is being read
+	if d.including[filename] {
 		return []error{fmt.Errorf("circular include detected: %s", filename)}
 	}
-	includedFiles[filename] = true
+	d.including[filename] = true
+	defer delete(d.including, filename)
 
 	file, err := os.Open(filename)
 	if err != nil {
@@ -391,11 +1111,37 @@ func parseFile(filename string, config interface{}, includedFiles map[string]boo
 	defer file.Close()
 
 	basePath := filepath.Dir(filename)
-	return parseReader(file, config, includedFiles, depth+1, basePath)
+	return d.parseReader(file, depth, basePath)
+}
+
+// start sets the default values of all the fields before anything is read.
+func (d *decoder) start() []error {
+	if err := d.setDefaultValues(reflect.ValueOf(d.config).Elem()); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// finish applies section inheritance and resolves the deferred values once the
+// whole file and its includes have been read.
+func (d *decoder) finish() []error {
+	errors := d.applyInheritance()
+	return append(errors, d.resolveDeferred()...)
 }
 
 // Parse parses the INI file content from an io.Reader and populates the config struct.
-func Parse(reader io.Reader, config interface{}) []error {
+func Parse(reader io.Reader, config interface{}, opts ...Option) []error {
+	fieldCache = sync.Map{} // Clear the field cache
+	d := newDecoder(config, opts...)
+	errors := append(d.start(), d.parseReader(reader, 0, "")...)
+	return append(errors, d.finish()...)
+}
+
+// ParseFile parses the named INI file and populates the config struct.
+// Relative include directives are resolved against the directory of the file.
+func ParseFile(filename string, config interface{}, opts ...Option) []error {
 	fieldCache = sync.Map{} // Clear the field cache
-	return parseReader(reader, config, make(map[string]bool), 0, "")
+	d := newDecoder(config, opts...)
+	errors := append(d.start(), d.parseFile(filename, 0)...)
+	return append(errors, d.finish()...)
 }
@@ -545,7 +545,7 @@ func TestSetConfigValue(t *testing.T) {
 	}
 
 	config := &TestConfig{}
-	err := setConfigValue(config, "", "name", "John Doe")
+	err := newDecoder(config).setConfigValue("", "name", "John Doe")
 	if err != nil {
 		t.Fatalf("Failed to set name: %v", err)
 	}
@@ -553,7 +553,7 @@ func TestSetConfigValue(t *testing.T) {
 		t.Errorf("Expected name to be 'John Doe', got '%s'", *config.Name)
 	}
 
-	err = setConfigValue(config, "", "age", "30")
+	err = newDecoder(config).setConfigValue("", "age", "30")
 	if err != nil {
 		t.Fatalf("Failed to set age: %v", err)
 	}
@@ -561,7 +561,7 @@ func TestSetConfigValue(t *testing.T) {
 		t.Errorf("Expected age to be 30, got %d", *config.Age)
 	}
 
-	err = setConfigValue(config, "", "score", "95.5")
+	err = newDecoder(config).setConfigValue("", "score", "95.5")
 	if err != nil {
 		t.Fatalf("Failed to set score: %v", err)
 	}
@@ -569,7 +569,7 @@ func TestSetConfigValue(t *testing.T) {
 		t.Errorf("Expected score to be 95.5, got %f", *config.Score)
 	}
 
-	err = setConfigValue(config, "", "active", "true")
+	err = newDecoder(config).setConfigValue("", "active", "true")
 	if err != nil {
 		t.Fatalf("Failed to set active: %v", err)
 	}
@@ -577,7 +577,7 @@ func TestSetConfigValue(t *testing.T) {
 		t.Errorf("Expected active to be true, got %v", *config.Active)
 	}
 
-	err = setConfigValue(config, "", "unknown", "value")
+	err = newDecoder(config).setConfigValue("", "unknown", "value")
 	if err == nil {
 		t.Fatal("Expected error for unknown field, got nil")
 	}
@@ -585,7 +585,7 @@ func TestSetConfigValue(t *testing.T) {
 
 func TestSetConfigValue_InvalidConfigType(t *testing.T) {
 	config := "invalid"
-	err := setConfigValue(config, "", "name", "John Doe")
+	err := newDecoder(config).setConfigValue("", "name", "John Doe")
 	if err == nil || !strings.Contains(err.Error(), "configuration must be a pointer to a struct") {
 		t.Fatalf("Expected error for invalid config type, got %v", err)
 	}
@@ -597,7 +597,7 @@ func TestSetStructValue_NoMatchingField(t *testing.T) {
 	}
 
 	config := &TestConfig{}
-	err := setStructValue(reflect.ValueOf(config).Elem(), "unknown", "value")
+	err := newDecoder(config).setStructValue(reflect.ValueOf(config).Elem(), "unknown", "value")
 	if err == nil || !strings.Contains(err.Error(), "no matching field found for key") {
 		t.Fatalf("Expected error for no matching field, got %v", err)
 	}
@@ -963,6 +963,92 @@ host = $DB_HOST
 	}
 }
 
+func TestParse_EnvVarModifiers(t *testing.T) {
+	vars := map[string]string{"DB_HOST": "env.db.local", "DB_USER": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+
+	iniContent := `
+app_name = ${APP_NAME:-DefaultApp}
+version = $$1.0
+
+[database]
+host = ${DB_HOST:?must be set}
+username = ${DB_USER:-admin}
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithEnvLookup(lookup))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with env var modifiers: %v", errors)
+	}
+
+	if config.AppName != "DefaultApp" {
+		t.Errorf("Expected app_name to use the default, got '%s'", config.AppName)
+	}
+	if config.Version == nil || *config.Version != "$1.0" {
+		t.Errorf("Expected $$ to be a literal $, got %v", config.Version)
+	}
+	if config.Database.Host != "env.db.local" || config.Database.Username != "admin" {
+		t.Errorf("Unexpected database config: %+v", config.Database)
+	}
+}
+
+func TestParse_RequiredEnvVar(t *testing.T) {
+	lookup := func(name string) (string, bool) { return "", false }
+
+	iniContent := `
+app_name = MyApp
+
+[database]
+password = ${DB_PASSWORD:?database password is required}
+username = ${DB_USER:?}
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithEnvLookup(lookup))
+	expected := []string{
+		"error at line 5: DB_PASSWORD: database password is required",
+		"error at line 6: DB_USER: parameter not set",
+	}
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errors)
+	}
+	for i, err := range errors {
+		if err.Error() != expected[i] {
+			t.Errorf("Expected error %q, got %q", expected[i], err.Error())
+		}
+	}
+}
+
+func TestParse_DisabledEnvExpansion(t *testing.T) {
+	type TemplateConfig struct {
+		Name     string
+		Template string `expand:"false"`
+	}
+
+	lookup := func(name string) (string, bool) { return "value", true }
+	iniContent := "name = ${NAME}\ntemplate = Hello ${USER}\n"
+
+	config := TemplateConfig{}
+	if errors := Parse(strings.NewReader(iniContent), &config, WithEnvLookup(lookup)); errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if config.Name != "value" || config.Template != "Hello ${USER}" {
+		t.Errorf("Expected expansion to be disabled for the tagged field, got %+v", config)
+	}
+
+	config = TemplateConfig{}
+	if errors := Parse(strings.NewReader(iniContent), &config, WithEnvLookup(lookup), WithEnvExpansion(false)); errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if config.Name != "${NAME}" {
+		t.Errorf("Expected expansion to be disabled, got %+v", config)
+	}
+}
+
 func TestParse_CaseInsensitiveKeys(t *testing.T) {
 	iniContent := `
 App_Name = MyApp
@@ -1389,7 +1475,7 @@ version = 1.0.0
 	mainIniFile.Close()
 
 	config := Config{}
-	errors := parseFile(mainIniFile.Name(), &config, make(map[string]bool), 0)
+	errors := newDecoder(&config).parseFile(mainIniFile.Name(), 0)
 	if errors != nil {
 		t.Fatalf("Failed to parse INI with include directive: %v", errors)
 	}
@@ -1444,7 +1530,7 @@ app_name = MyApp
 	mainIniFile.Close()
 
 	config := Config{}
-	errors := parseFile(mainIniFile.Name(), &config, make(map[string]bool), 0)
+	errors := newDecoder(&config).parseFile(mainIniFile.Name(), 0)
 	if errors == nil || !strings.Contains(errors[0].Error(), "circular include detected") {
 		t.Fatalf("Expected error for circular include, got %v", errors)
 	}
@@ -1469,7 +1555,7 @@ app_name = MyApp
 	mainIniFile.Close()
 
 	config := Config{}
-	errors := parseFile(mainIniFile.Name(), &config, make(map[string]bool), 0)
+	errors := newDecoder(&config).parseFile(mainIniFile.Name(), 0)
 	if errors == nil || !strings.Contains(errors[0].Error(), "failed to open file") {
 		t.Fatalf("Expected error for file not found, got %v", errors)
 	}
@@ -1552,7 +1638,7 @@ app_name = MyApp
 	}
 
 	config := Config{}
-	errors := parseFile(includeFileNames[0], &config, make(map[string]bool), 0)
+	errors := newDecoder(&config).parseFile(includeFileNames[0], 0)
 	if errors == nil || !strings.Contains(errors[0].Error(), "maximum include depth exceeded") {
 		t.Fatalf("Expected error for maximum include depth exceeded, got %v", errors)
 	}
@@ -1819,3 +1905,460 @@ app_name = MyApp
 		t.Errorf("Expected timeout to be nil, got '%v'", *config.Timeout)
 	}
 }
+
+func TestParse_InlineComments(t *testing.T) {
+	iniContent := `
+app_name = MyApp ; the application
+version = 1.0.0 # semantic version
+
+[server] ; main server
+host = localhost;not a comment
+port = 8080 ; http port
+description = "quoted ; value" ; trailing
+notes = first line ; one
+        second line # two
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithInlineComments())
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with inline comments: %v", errors)
+	}
+
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be 'MyApp', got '%s'", config.AppName)
+	}
+	if *config.Version != "1.0.0" {
+		t.Errorf("Expected version to be '1.0.0', got '%s'", *config.Version)
+	}
+	if config.Server.Host != "localhost;not a comment" {
+		t.Errorf("Expected server host to keep unspaced semicolon, got '%s'", config.Server.Host)
+	}
+	if config.Server.Port != 8080 {
+		t.Errorf("Expected server port to be 8080, got %d", config.Server.Port)
+	}
+	if config.Server.Description != "quoted ; value" {
+		t.Errorf("Expected description to be 'quoted ; value', got '%s'", config.Server.Description)
+	}
+	if config.Server.Notes != "first line\nsecond line" {
+		t.Errorf("Expected multiline notes without comments, got '%s'", config.Server.Notes)
+	}
+}
+
+func TestParse_InlineCommentsCustomPrefix(t *testing.T) {
+	iniContent := `
+app_name = MyApp # not a comment ; comment
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithInlineComments(";"))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with inline comments: %v", errors)
+	}
+
+	if config.AppName != "MyApp # not a comment" {
+		t.Errorf("Expected app_name to be 'MyApp # not a comment', got '%s'", config.AppName)
+	}
+}
+
+func TestParse_InlineCommentsDisabled(t *testing.T) {
+	iniContent := `
+[server]
+port = 8080 ; http port
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors == nil || !strings.Contains(errors[0].Error(), "invalid value for field type uint") {
+		t.Fatalf("Expected error for inline comment without option, got %v", errors)
+	}
+}
+
+func TestParse_QuotedValues(t *testing.T) {
+	iniContent := `
+app_name = "  My App  "
+version = '1.0.0'
+
+[server]
+host = "local\thost"
+description = "line one\nline \"two\" \\ \u00e9"
+notes = 'it\'s ; here'
+username = "quoted"
+           '  second  '
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with quoted values: %v", errors)
+	}
+
+	if config.AppName != "  My App  " {
+		t.Errorf("Expected app_name to keep inner whitespace, got '%s'", config.AppName)
+	}
+	if *config.Version != "1.0.0" {
+		t.Errorf("Expected version to be '1.0.0', got '%s'", *config.Version)
+	}
+	if config.Server.Host != "local\thost" {
+		t.Errorf("Expected host with tab, got %q", config.Server.Host)
+	}
+	if config.Server.Description != "line one\nline \"two\" \\ \u00e9" {
+		t.Errorf("Unexpected description: %q", config.Server.Description)
+	}
+	if config.Server.Notes != "it's ; here" {
+		t.Errorf("Unexpected notes: %q", config.Server.Notes)
+	}
+	if *config.Server.Username != "quoted\n  second  " {
+		t.Errorf("Unexpected multiline username: %q", *config.Server.Username)
+	}
+}
+
+func TestParse_PartlyQuotedValues(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{`"Hello" world`, `"Hello" world`},
+		{`'90s`, `'90s`},
+		{`"unterminated`, `"unterminated`},
+		{`"one" "two"`, `"one" "two"`},
+		{`say "hi"`, `say "hi"`},
+	}
+
+	for _, test := range tests {
+		config := Config{}
+		if errors := Parse(strings.NewReader("app_name = "+test.value), &config); errors != nil {
+			t.Errorf("Failed to parse %s: %v", test.value, errors)
+			continue
+		}
+		if config.AppName != test.expected {
+			t.Errorf("Expected %s to be read literally, got %q", test.value, config.AppName)
+		}
+	}
+}
+
+func TestParse_InvalidQuotedValues(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{`"bad\q"`, "error at line 1: invalid escape sequence '\\q'"},
+		{`"bad\u12"`, "error at line 1: invalid unicode escape"},
+	}
+
+	for _, test := range tests {
+		config := Config{}
+		errors := Parse(strings.NewReader("app_name = "+test.value), &config)
+		if errors == nil || !strings.Contains(errors[0].Error(), test.expected) {
+			t.Errorf("Expected error %q for %s, got %v", test.expected, test.value, errors)
+		}
+	}
+}
+
+func TestParse_BackslashContinuation(t *testing.T) {
+	os.Setenv("NOTES_SUFFIX", "expanded")
+	defer os.Unsetenv("NOTES_SUFFIX")
+
+	iniContent := `
+[server]
+description = first \
+second \
+    third
+notes = one \
+"  two  " \
+${NOTES_SUFFIX}
+host = localhost
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithContinuation(ContinuationBackslash))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with backslash continuation: %v", errors)
+	}
+
+	if config.Server.Description != "first\nsecond\nthird" {
+		t.Errorf("Unexpected description: %q", config.Server.Description)
+	}
+	if config.Server.Notes != "one\n  two  \nexpanded" {
+		t.Errorf("Unexpected notes: %q", config.Server.Notes)
+	}
+	if config.Server.Host != "localhost" {
+		t.Errorf("Expected server host to be 'localhost', got '%s'", config.Server.Host)
+	}
+}
+
+func TestParse_BackslashContinuationAtEOF(t *testing.T) {
+	config := Config{}
+	errors := Parse(strings.NewReader("app_name = MyApp \\"), &config, WithContinuation(ContinuationBackslash))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with trailing backslash: %v", errors)
+	}
+	if config.AppName != "MyApp" {
+		t.Errorf("Expected app_name to be 'MyApp', got '%s'", config.AppName)
+	}
+}
+
+func TestParse_IndentContinuationDisabled(t *testing.T) {
+	iniContent := `
+[server]
+    host = localhost
+    port = 8080
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithContinuation(ContinuationBackslash))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with indented keys: %v", errors)
+	}
+	if config.Server.Host != "localhost" || config.Server.Port != 8080 {
+		t.Errorf("Expected indented keys to be parsed, got host '%s' and port %d", config.Server.Host, config.Server.Port)
+	}
+}
+
+func TestParse_HeredocContinuation(t *testing.T) {
+	os.Setenv("HEREDOC_VAR", "expanded")
+	defer os.Unsetenv("HEREDOC_VAR")
+
+	iniContent := `
+app_name = """My App"""
+
+[server]
+description = """
+  indented line
+; not a comment
+!include not_a_directive.ini
+${HEREDOC_VAR}
+"""
+notes = '''first line
+  ${HEREDOC_VAR}'''
+host = localhost
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithContinuation(ContinuationIndent|ContinuationHeredoc))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with heredoc continuation: %v", errors)
+	}
+
+	if config.AppName != "My App" {
+		t.Errorf("Expected app_name to be 'My App', got '%s'", config.AppName)
+	}
+	expected := "  indented line\n; not a comment\n!include not_a_directive.ini\nexpanded"
+	if config.Server.Description != expected {
+		t.Errorf("Expected description %q, got %q", expected, config.Server.Description)
+	}
+	if config.Server.Notes != "first line\n  ${HEREDOC_VAR}" {
+		t.Errorf("Expected raw notes, got %q", config.Server.Notes)
+	}
+	if config.Server.Host != "localhost" {
+		t.Errorf("Expected server host to be 'localhost', got '%s'", config.Server.Host)
+	}
+}
+
+func TestParse_HeredocSlice(t *testing.T) {
+	iniContent := `
+strings = """
+one
+two
+"""
+`
+
+	config := PrimitiveSliceConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithContinuation(ContinuationHeredoc))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with heredoc slice: %v", errors)
+	}
+	if !reflect.DeepEqual(config.Strings, []string{"one", "two"}) {
+		t.Errorf("Expected strings [one two], got %v", config.Strings)
+	}
+}
+
+func TestParse_UnterminatedHeredoc(t *testing.T) {
+	iniContent := `
+[server]
+description = """
+never closed
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithContinuation(ContinuationHeredoc))
+	if errors == nil || !strings.Contains(errors[0].Error(), "unterminated multiline value starting at line 3") {
+		t.Fatalf("Expected error for unterminated heredoc, got %v", errors)
+	}
+}
+
+type SeparatedSliceConfig struct {
+	Hosts   []string  `ini:"hosts" sep:","`
+	Ports   []uint    `ini:"ports" sep:","`
+	Weights []float64 `ini:"weights" sep:";"`
+	Tags    *[]string `ini:"tags" sep:" "`
+	Lines   []string  `ini:"lines"`
+	Zones   []string  `ini:"zones" sep:"," default:"a, b"`
+}
+
+func TestParse_SeparatedSlices(t *testing.T) {
+	iniContent := `
+hosts = alpha, "beta, gamma" , 'delta'
+ports = 80,443,
+        8080
+weights = 0.5;1.5
+tags = one two  three
+lines = first, line
+        second, line
+`
+
+	config := SeparatedSliceConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with separated slices: %v", errors)
+	}
+
+	if !reflect.DeepEqual(config.Hosts, []string{"alpha", "beta, gamma", "delta"}) {
+		t.Errorf("Unexpected hosts: %q", config.Hosts)
+	}
+	if !reflect.DeepEqual(config.Ports, []uint{80, 443, 8080}) {
+		t.Errorf("Unexpected ports: %v", config.Ports)
+	}
+	if !reflect.DeepEqual(config.Weights, []float64{0.5, 1.5}) {
+		t.Errorf("Unexpected weights: %v", config.Weights)
+	}
+	if config.Tags == nil || !reflect.DeepEqual(*config.Tags, []string{"one", "two", "three"}) {
+		t.Errorf("Unexpected tags: %v", config.Tags)
+	}
+	if !reflect.DeepEqual(config.Lines, []string{"first, line", "second, line"}) {
+		t.Errorf("Unexpected lines: %q", config.Lines)
+	}
+	if !reflect.DeepEqual(config.Zones, []string{"a", "b"}) {
+		t.Errorf("Unexpected default zones: %q", config.Zones)
+	}
+}
+
+func TestParse_DefaultSliceSeparator(t *testing.T) {
+	iniContent := `
+hosts = a; b
+lines = first, second
+`
+
+	config := SeparatedSliceConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithSliceSeparator(","))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with default slice separator: %v", errors)
+	}
+
+	if !reflect.DeepEqual(config.Hosts, []string{"a; b"}) {
+		t.Errorf("Expected sep tag to take precedence, got %q", config.Hosts)
+	}
+	if !reflect.DeepEqual(config.Lines, []string{"first", "second"}) {
+		t.Errorf("Unexpected lines: %q", config.Lines)
+	}
+
+	custom := CustomSliceConfig{}
+	errors = Parse(strings.NewReader("values = a, b\n"), &custom, WithSliceSeparator(","))
+	if errors != nil {
+		t.Fatalf("Failed to parse custom slice: %v", errors)
+	}
+	if !reflect.DeepEqual([]string(custom.Values), []string{"a, b"}) {
+		t.Errorf("Expected TextUnmarshaler slice to receive the whole value, got %q", custom.Values)
+	}
+}
+
+func TestParse_InvalidSeparatedSlices(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"ports = 80, http", "error at line 1: invalid value for field type uint: http"},
+		{`hosts = "bad\q", b`, "error at line 1: invalid escape sequence '\\q'"},
+	}
+
+	for _, test := range tests {
+		config := SeparatedSliceConfig{}
+		errors := Parse(strings.NewReader(test.line), &config)
+		if errors == nil || !strings.Contains(errors[0].Error(), test.expected) {
+			t.Errorf("Expected error %q for %s, got %v", test.expected, test.line, errors)
+		}
+	}
+}
+
+type ArrayKeyConfig struct {
+	Extensions []string       `ini:"extension"`
+	Servers    []string       `ini:"servers"`
+	Ports      [3]uint        `ini:"ports"`
+	Limits     map[string]int `ini:"limits"`
+	Codes      map[int]string `ini:"codes"`
+	Name       string         `ini:"name"`
+}
+
+func TestParse_ArrayKeys(t *testing.T) {
+	iniContent := `
+name = php ; a comment
+extension[] = curl
+extension[] = "mbstring"
+servers[2] = c
+servers[0] = a
+ports[] = 80
+ports[] = 443
+ports[2] = 8080
+limits[Memory] = 128
+limits[ files ] = 10
+codes[404] = not found
+`
+
+	config := ArrayKeyConfig{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithDialect(DialectPHP))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI with array keys: %v", errors)
+	}
+
+	if config.Name != "php" {
+		t.Errorf("Expected name to be 'php', got '%s'", config.Name)
+	}
+	if !reflect.DeepEqual(config.Extensions, []string{"curl", "mbstring"}) {
+		t.Errorf("Unexpected extensions: %q", config.Extensions)
+	}
+	if !reflect.DeepEqual(config.Servers, []string{"a", "", "c"}) {
+		t.Errorf("Unexpected servers: %q", config.Servers)
+	}
+	if config.Ports != [3]uint{80, 443, 8080} {
+		t.Errorf("Unexpected ports: %v", config.Ports)
+	}
+	if !reflect.DeepEqual(config.Limits, map[string]int{"Memory": 128, "files": 10}) {
+		t.Errorf("Unexpected limits: %v", config.Limits)
+	}
+	if !reflect.DeepEqual(config.Codes, map[int]string{404: "not found"}) {
+		t.Errorf("Unexpected codes: %v", config.Codes)
+	}
+}
+
+func TestParse_ArrayKeysErrors(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"servers[x] = a", "error at line 1: invalid index 'x' for slice field 'servers'"},
+		{"ports[3] = 1", "error at line 1: index 3 out of range for array field 'ports' of length 3"},
+		{"servers[4611686018427387904] = a", "error at line 1: index 4611686018427387904 out of range for slice field 'servers' of length 0"},
+		{"servers[100000000] = a", "error at line 1: index 100000000 out of range for slice field 'servers' of length 0"},
+		{"servers[0] = a\nservers[1026] = b", "error at line 2: index 1026 out of range for slice field 'servers' of length 1"},
+		{"limits[] = 1", "error at line 1: map field 'limits' requires an index"},
+		{"codes[abc] = x", "error at line 1: invalid value for field type int: abc"},
+		{"name[0] = x", "error at line 1: field for key 'name[0]' is not a slice, array or map"},
+		{"missing[] = x", "error at line 1: no matching field found for key 'missing'"},
+	}
+
+	for _, test := range tests {
+		config := ArrayKeyConfig{}
+		errors := Parse(strings.NewReader(test.line), &config, WithArrayKeys())
+		if errors == nil || !strings.Contains(errors[0].Error(), test.expected) {
+			t.Errorf("Expected error %q for %s, got %v", test.expected, test.line, errors)
+		}
+	}
+}
+
+func TestParse_ArrayKeysDisabled(t *testing.T) {
+	config := ArrayKeyConfig{}
+	errors := Parse(strings.NewReader("extension[] = curl\n"), &config)
+	if errors == nil || !strings.Contains(errors[0].Error(), "invalid key name at line 1: extension[]") {
+		t.Fatalf("Expected error for array key without option, got %v", errors)
+	}
+}
@@ -0,0 +1,52 @@
+package simpleini
+
+import (
+	"strings"
+)
+
+// profilePrefix starts a section header qualified by a profile, as in
+// [profile prod.database].
+const profilePrefix = "profile "
+
+// WithProfile sets the active profile. Sections qualified by it, such as
+// [database@prod] or [profile prod.database], are applied after the rest of the
+// file and override the unqualified section. Sections qualified by any other
+// profile are ignored, as are all qualified sections without an active profile.
+func WithProfile(name string) Option {
+	return func(o *options) {
+		o.profile = name
+	}
+}
+
+// cutProfile splits a section header qualified by a profile into the section and
+// the profile. It reports false if the header is not qualified. The profile of
+// [profile prod] applies to the top level, so its section is empty.
+func cutProfile(header string) (section, profile string, found bool) {
+	if rest, ok := strings.CutPrefix(header, profilePrefix); ok && !strings.ContainsAny(rest, `"`) {
+		profile, section, _ = strings.Cut(strings.TrimSpace(rest), ".")
+		return section, profile, true
+	}
+	if i := strings.LastIndexByte(header, '@'); i >= 0 && !strings.ContainsAny(header[i:], `"`) {
+		return strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]), true
+	}
+	return header, "", false
+}
+
+// isValidProfile checks if the profile name is not empty and contains only
+// letters, digits, underscores and dashes.
+func isValidProfile(profile string) bool {
+	if profile == "" {
+		return false
+	}
+	for i := 0; i < len(profile); i++ {
+		if !isWordByte(profile[i]) && profile[i] != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// isActiveProfile checks if the profile is the active profile, ignoring case.
+func (d *decoder) isActiveProfile(profile string) bool {
+	return d.opts.profile != "" && strings.EqualFold(profile, d.opts.profile)
+}
@@ -0,0 +1,144 @@
+package simpleini
+
+import (
+	"strings"
+	"testing"
+)
+
+type ProfileDatabase struct {
+	Host string
+	Port int
+}
+
+type ProfileConfig struct {
+	Debug    bool
+	Database ProfileDatabase
+}
+
+const profileContent = `
+debug = true
+
+[database@prod]
+host = db.example.com
+
+[database]
+host = localhost
+port = 5432
+
+[profile staging.database]
+host = staging.example.com
+port = 6432
+
+[profile prod]
+debug = false
+
+[database@dev]
+unknown_key = ignored
+`
+
+func TestParse_Profiles(t *testing.T) {
+	tests := []struct {
+		profile  string
+		expected ProfileConfig
+	}{
+		{"", ProfileConfig{Debug: true, Database: ProfileDatabase{Host: "localhost", Port: 5432}}},
+		{"prod", ProfileConfig{Debug: false, Database: ProfileDatabase{Host: "db.example.com", Port: 5432}}},
+		{"PROD", ProfileConfig{Debug: false, Database: ProfileDatabase{Host: "db.example.com", Port: 5432}}},
+		{"staging", ProfileConfig{Debug: true, Database: ProfileDatabase{Host: "staging.example.com", Port: 6432}}},
+		{"qa", ProfileConfig{Debug: true, Database: ProfileDatabase{Host: "localhost", Port: 5432}}},
+	}
+
+	for _, test := range tests {
+		config := ProfileConfig{}
+		errors := Parse(strings.NewReader(profileContent), &config, WithProfile(test.profile))
+		if errors != nil {
+			t.Errorf("profile %q: failed to parse INI: %v", test.profile, errors)
+			continue
+		}
+		if config != test.expected {
+			t.Errorf("profile %q: expected %+v, got %+v", test.profile, test.expected, config)
+		}
+	}
+}
+
+func TestParse_ProfileWithReferences(t *testing.T) {
+	type Config struct {
+		Database ProfileDatabase
+		URL      string `ini:"url"`
+	}
+	iniContent := `
+url = ${database.host}:${database.port}
+
+[database]
+host = localhost
+port = 5432
+
+[database@prod]
+host = db.example.com
+`
+
+	config := Config{}
+	errors := Parse(strings.NewReader(iniContent), &config, WithProfile("prod"))
+	if errors != nil {
+		t.Fatalf("Failed to parse INI: %v", errors)
+	}
+	if config.URL != "db.example.com:5432" {
+		t.Errorf("Expected the reference to use the profile value, got %q", config.URL)
+	}
+}
+
+func TestParse_InvalidProfile(t *testing.T) {
+	config := ProfileConfig{}
+	errors := Parse(strings.NewReader("[database@bad profile]\nhost = x\n"), &config, WithProfile("prod"))
+	if errors == nil || errors[0].Error() != "invalid profile name at line 1: bad profile" {
+		t.Errorf("Expected an invalid profile error, got %v", errors)
+	}
+}
+
+func TestParse_InactiveProfileSections(t *testing.T) {
+	type Config struct {
+		Database *ProfileDatabase
+		Worker   map[string]ProfileDatabase
+	}
+	content := "[database@dev]\nhost = dev.example.com\n\n[worker.z@dev]\nport = 1\n"
+
+	for _, profile := range []string{"", "prod"} {
+		config := Config{}
+		errors := Parse(strings.NewReader(content), &config, WithProfile(profile))
+		if errors != nil {
+			t.Errorf("profile %q: failed to parse INI: %v", profile, errors)
+			continue
+		}
+		if config.Database != nil {
+			t.Errorf("profile %q: expected no database section, got %+v", profile, config.Database)
+		}
+		if _, ok := config.Worker["z"]; ok {
+			t.Errorf("profile %q: expected no worker section, got %+v", profile, config.Worker)
+		}
+	}
+}
+
+func TestCutProfile(t *testing.T) {
+	tests := []struct {
+		input   string
+		section string
+		profile string
+		found   bool
+	}{
+		{"database@prod", "database", "prod", true},
+		{"database @ prod", "database", "prod", true},
+		{"profile prod.database", "database", "prod", true},
+		{"profile prod.server.logging", "server.logging", "prod", true},
+		{"profile prod", "", "prod", true},
+		{"database", "database", "", false},
+		{`remote "a@b"`, `remote "a@b"`, "", false},
+		{`remote "a@b"@prod`, `remote "a@b"`, "prod", true},
+	}
+
+	for _, test := range tests {
+		section, profile, found := cutProfile(test.input)
+		if section != test.section || profile != test.profile || found != test.found {
+			t.Errorf("cutProfile(%q) = (%q, %q, %v); expected (%q, %q, %v)", test.input, section, profile, found, test.section, test.profile, test.found)
+		}
+	}
+}
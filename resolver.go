@@ -0,0 +1,65 @@
+package simpleini
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Resolver resolves references of the form ${scheme:ref} in values, such as
+// ${file:/run/secrets/db} or ${secret:vault/db#password}. It is registered for
+// a scheme with WithResolver and receives the part after the colon.
+type Resolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref).
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// FileResolver resolves a reference to the contents of the named file, without
+// a trailing newline, as used for secrets mounted as files.
+type FileResolver struct{}
+
+// Resolve reads the file.
+func (FileResolver) Resolve(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
+
+// WithResolver registers a resolver for ${scheme:ref} references. References
+// are resolved together with environment variables, so they are not resolved
+// when expansion is disabled.
+//
+//	simpleini.WithResolver("file", simpleini.FileResolver{})
+func WithResolver(scheme string, resolver Resolver) Option {
+	return func(o *options) {
+		if o.resolvers == nil {
+			o.resolvers = make(map[string]Resolver)
+		}
+		o.resolvers[scheme] = resolver
+	}
+}
+
+// resolve resolves a ${scheme:ref} reference with the resolver registered for
+// the scheme. It reports false if no resolver is registered.
+func (d *decoder) resolve(name string) (string, bool, error) {
+	scheme, ref, ok := strings.Cut(name, ":")
+	resolver := d.opts.resolvers[scheme]
+	if !ok || resolver == nil {
+		return "", false, nil
+	}
+	value, err := resolver.Resolve(ref)
+	if err != nil {
+		return "", true, fmt.Errorf("cannot resolve '${%s}': %w", name, err)
+	}
+	return value, true, nil
+}
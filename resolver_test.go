@@ -0,0 +1,115 @@
+package simpleini
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse_Resolvers(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	vault := ResolverFunc(func(ref string) (string, error) {
+		if ref == "vault/db#username" {
+			return "dbadmin", nil
+		}
+		return "", errors.New("secret not found")
+	})
+
+	iniContent := `
+[database]
+username = ${secret:vault/db#username}
+password = ${file:` + secretFile + `}
+`
+
+	config := Config{}
+	errs := Parse(strings.NewReader(iniContent), &config, WithResolver("file", FileResolver{}), WithResolver("secret", vault))
+	if errs != nil {
+		t.Fatalf("Failed to parse INI with resolvers: %v", errs)
+	}
+
+	if config.Database.Username != "dbadmin" {
+		t.Errorf("Expected username from the secret resolver, got %q", config.Database.Username)
+	}
+	if config.Database.Password == nil || *config.Database.Password != "s3cret" {
+		t.Errorf("Expected password from the file without a trailing newline, got %v", config.Database.Password)
+	}
+}
+
+func TestParse_ResolverErrors(t *testing.T) {
+	vault := ResolverFunc(func(ref string) (string, error) {
+		return "", errors.New("secret not found")
+	})
+
+	iniContent := `
+[database]
+host = db.local
+password = ${secret:vault/db#password}
+`
+
+	config := Config{}
+	errs := Parse(strings.NewReader(iniContent), &config, WithResolver("secret", vault))
+	if len(errs) != 1 || errs[0].Error() != "error at line 4: cannot resolve '${secret:vault/db#password}': secret not found" {
+		t.Fatalf("Expected a resolver error at line 4, got %v", errs)
+	}
+}
+
+func TestParse_UnknownResolverScheme(t *testing.T) {
+	iniContent := `
+[database]
+host = db.local
+password = ${flie:/run/secrets/db}
+`
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"no resolvers", nil},
+		{"misspelled scheme", []Option{WithResolver("file", FileResolver{})}},
+	}
+
+	for _, test := range tests {
+		config := Config{}
+		errs := Parse(strings.NewReader(iniContent), &config, test.opts...)
+		expected := "error at line 4: no resolver registered for scheme 'flie' in '${flie:/run/secrets/db}'"
+		if len(errs) != 1 || errs[0].Error() != expected {
+			t.Errorf("%s: expected error %q, got %v", test.name, expected, errs)
+		}
+	}
+}
+
+func TestParse_ResolverWithExtendedInterpolation(t *testing.T) {
+	vault := ResolverFunc(func(ref string) (string, error) { return "from-" + ref, nil })
+
+	config := InterpolationConfig{}
+	errs := Parse(strings.NewReader("[paths]\nbase = ${secret:base}\nlogs = ${paths:base}/logs\n"), &config,
+		WithInterpolation(InterpolationExtended), WithResolver("secret", vault))
+	if errs != nil {
+		t.Fatalf("Failed to parse INI content: %v", errs)
+	}
+	if config.Paths.Base != "from-base" || config.Paths.Logs != "from-base/logs" {
+		t.Errorf("Unexpected paths: %+v", config.Paths)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("line1\nline2\r\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	value, err := FileResolver{}.Resolve(file)
+	if err != nil || value != "line1\nline2" {
+		t.Errorf("Resolve(%q) = %q, %v; expected the contents without the trailing newline", file, value, err)
+	}
+	if _, err := (FileResolver{}).Resolve(file + ".missing"); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
@@ -0,0 +1,221 @@
+package simpleini
+
+import (
+	"encoding"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+)
+
+// RedactedValue replaces the value of a secret field in Write, Redacted and
+// LogValue.
+const RedactedValue = "REDACTED"
+
+// isSecret checks if the field is tagged secret:"true" or has the secret option
+// in its ini tag, as in ini:"password,secret".
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true" || hasTagOption(field, "secret")
+}
+
+// WithRevealedSecrets makes Write emit the values of secret fields instead of
+// RedactedValue.
+func WithRevealedSecrets() Option {
+	return func(o *options) {
+		o.revealSecrets = true
+	}
+}
+
+// Redacted returns a deep copy of the config in which every secret field that
+// is set holds RedactedValue. String fields, string pointers and slices of
+// strings are replaced element by element; secret fields of other types are
+// cleared.
+func Redacted[T any](config *T) *T {
+	redacted := new(T)
+	if config != nil {
+		copyRedacted(reflect.ValueOf(redacted).Elem(), reflect.ValueOf(config).Elem())
+	}
+	return redacted
+}
+
+// copyRedacted deep copies src into dst, redacting secret fields.
+func copyRedacted(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		// Copy unexported state first, then replace the exported fields
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			field := src.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if isSecret(field) && !src.Field(i).IsZero() {
+				redactValue(dst.Field(i), src.Field(i))
+				continue
+			}
+			copyRedacted(dst.Field(i), src.Field(i))
+		}
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.New(src.Type().Elem()))
+		copyRedacted(dst.Elem(), src.Elem())
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		iter := src.MapRange()
+		for iter.Next() {
+			elem := reflect.New(src.Type().Elem()).Elem()
+			copyRedacted(elem, iter.Value())
+			dst.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyRedacted(dst.Index(i), src.Index(i))
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// redactValue sets dst to the redacted form of the secret value src.
+func redactValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.String:
+		dst.SetString(RedactedValue)
+	case reflect.Ptr:
+		dst.Set(reflect.New(src.Type().Elem()))
+		redactValue(dst.Elem(), src.Elem())
+	case reflect.Slice:
+		if src.Type().Elem().Kind() == reflect.String {
+			dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+			for i := 0; i < src.Len(); i++ {
+				dst.Index(i).SetString(RedactedValue)
+			}
+			return
+		}
+		dst.Set(reflect.Zero(src.Type()))
+	default:
+		dst.Set(reflect.Zero(src.Type()))
+	}
+}
+
+// LogValue returns a slog.Value for the config with secret fields redacted.
+// Structs become groups keyed by their INI names. A config type can implement
+// slog.LogValuer with it:
+//
+//	func (c Config) LogValue() slog.Value {
+//		return simpleini.LogValue(c)
+//	}
+func LogValue(config interface{}) slog.Value {
+	v := reflect.ValueOf(config)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return slog.AnyValue(config)
+	}
+	return slog.GroupValue(structAttrs(v)...)
+}
+
+// structAttrs returns the attributes for the exported fields of a struct.
+// Fields of promoted structs are included directly.
+func structAttrs(v reflect.Value) []slog.Attr {
+	var attrs []slog.Attr
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		fieldValue := v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			attrs = append(attrs, structAttrs(fieldValue)...)
+			continue
+		}
+
+		name := fieldTagName(field)
+		if name == "" {
+			name = pascalToSnake(field.Name)
+		}
+		if isSecret(field) && !fieldValue.IsZero() {
+			attrs = append(attrs, slog.String(name, RedactedValue))
+			continue
+		}
+		attrs = append(attrs, slog.Attr{Key: name, Value: logValue(fieldValue)})
+	}
+	return attrs
+}
+
+// logValue returns the slog.Value of a field. Structs and maps of sections
+// become groups, and other values are logged as they are.
+func logValue(v reflect.Value) slog.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return slog.AnyValue(nil)
+		}
+		return logValue(v.Elem())
+	}
+	if !v.CanInterface() {
+		return slog.StringValue(fmt.Sprintf("%v", v))
+	}
+	if isLoggedAsIs(v.Type()) {
+		return slog.AnyValue(v.Interface())
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct:
+		return slog.GroupValue(structAttrs(v)...)
+	case isSectionMap(v.Type()):
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		attrs := make([]slog.Attr, 0, len(keys))
+		for _, key := range keys {
+			attrs = append(attrs, slog.Attr{Key: key.String(), Value: logValue(v.MapIndex(key))})
+		}
+		return slog.GroupValue(attrs...)
+	default:
+		return slog.AnyValue(v.Interface())
+	}
+}
+
+// isLoggedAsIs checks if values of the type log themselves, so that structs
+// such as time.Time are not expanded into groups. A Stringer or TextMarshaler
+// with secret fields is expanded, so that its secrets are redacted.
+func isLoggedAsIs(t reflect.Type) bool {
+	if t.Implements(reflect.TypeFor[slog.LogValuer]()) {
+		return true
+	}
+	if !t.Implements(reflect.TypeFor[fmt.Stringer]()) && !t.Implements(reflect.TypeFor[encoding.TextMarshaler]()) {
+		return false
+	}
+	return !hasSecretFields(t, make(map[reflect.Type]bool))
+}
+
+// hasSecretFields checks if the type is a struct with exported secret fields,
+// directly or in the structs and sections it contains.
+func hasSecretFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return false
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && (isSecret(field) || hasSecretFields(field.Type, visited)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+package simpleini
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SecretDatabase struct {
+	Host     string
+	Password string   `ini:"password,secret"`
+	Token    *string  `secret:"true"`
+	Keys     []string `secret:"true" sep:","`
+	Pin      int      `secret:"true"`
+}
+
+type SecretConfig struct {
+	Name     string
+	Database SecretDatabase
+	Replicas map[string]*SecretDatabase
+}
+
+func newSecretConfig() *SecretConfig {
+	token := "s3cr3t-token"
+	return &SecretConfig{
+		Name: "app",
+		Database: SecretDatabase{
+			Host:     "db.local",
+			Password: "hunter2",
+			Token:    &token,
+			Keys:     []string{"a", "b"},
+			Pin:      1234,
+		},
+		Replicas: map[string]*SecretDatabase{
+			"eu": {Host: "eu.db.local", Password: "eu-secret"},
+		},
+	}
+}
+
+func TestWrite_RedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, newSecretConfig()); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	output := buf.String()
+	for _, secret := range []string{"hunter2", "s3cr3t-token", "1234", "eu-secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", secret, output)
+		}
+	}
+	for _, line := range []string{"password = REDACTED", "token = REDACTED", "keys = REDACTED", "pin = REDACTED", "host = db.local"} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected line %q, got:\n%s", line, output)
+		}
+	}
+
+	buf.Reset()
+	if err := Write(&buf, newSecretConfig(), WithRevealedSecrets()); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if !strings.Contains(buf.String(), "password = hunter2\n") || !strings.Contains(buf.String(), "keys = a, b\n") {
+		t.Errorf("Expected secrets to be revealed, got:\n%s", buf.String())
+	}
+
+	// Unset secrets are written empty rather than redacted
+	buf.Reset()
+	if err := Write(&buf, &SecretConfig{}); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if strings.Contains(buf.String(), RedactedValue) {
+		t.Errorf("Expected empty secrets to stay empty, got:\n%s", buf.String())
+	}
+}
+
+func TestParse_SecretTagOption(t *testing.T) {
+	config := SecretConfig{}
+	errors := Parse(strings.NewReader("[database]\npassword = hunter2\n"), &config)
+	if errors != nil {
+		t.Fatalf("Failed to parse INI content: %v", errors)
+	}
+	if config.Database.Password != "hunter2" {
+		t.Errorf("Expected the tag name before the options to match, got %q", config.Database.Password)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	config := newSecretConfig()
+	redacted := Redacted(config)
+
+	if redacted.Database.Password != RedactedValue || *redacted.Database.Token != RedactedValue {
+		t.Errorf("Expected string secrets to be redacted, got %+v", redacted.Database)
+	}
+	if len(redacted.Database.Keys) != 2 || redacted.Database.Keys[0] != RedactedValue || redacted.Database.Pin != 0 {
+		t.Errorf("Unexpected redacted keys and pin: %+v", redacted.Database)
+	}
+	if redacted.Database.Host != "db.local" || redacted.Name != "app" {
+		t.Errorf("Expected other fields to be copied, got %+v", redacted)
+	}
+	if redacted.Replicas["eu"].Password != RedactedValue {
+		t.Errorf("Expected secrets in section maps to be redacted, got %+v", redacted.Replicas["eu"])
+	}
+
+	// The original is unchanged
+	if config.Database.Password != "hunter2" || *config.Database.Token != "s3cr3t-token" || config.Replicas["eu"].Password != "eu-secret" {
+		t.Errorf("Expected the original config to be unchanged, got %+v", config)
+	}
+	redacted.Replicas["eu"].Host = "changed"
+	if config.Replicas["eu"].Host != "eu.db.local" {
+		t.Error("Expected Redacted to return a deep copy")
+	}
+}
+
+func TestLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.Info("loaded", "config", LogValue(newSecretConfig()))
+	expected := "level=INFO msg=loaded config.name=app config.database.host=db.local config.database.password=REDACTED " +
+		"config.database.token=REDACTED config.database.keys=REDACTED config.database.pin=REDACTED " +
+		"config.replicas.eu.host=eu.db.local config.replicas.eu.password=REDACTED config.replicas.eu.token=<nil> " +
+		"config.replicas.eu.keys=[] config.replicas.eu.pin=0\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+type StringerDatabase struct {
+	Host     string
+	Password string `secret:"true"`
+}
+
+func (d StringerDatabase) String() string {
+	return d.Host + ":" + d.Password
+}
+
+type MarshalerDatabase struct {
+	Host  string
+	Token string `ini:"token,secret"`
+}
+
+func (d MarshalerDatabase) MarshalText() ([]byte, error) {
+	return []byte(d.Host + ":" + d.Token), nil
+}
+
+func TestLogValue_SecretsInStringers(t *testing.T) {
+	type Config struct {
+		Primary  StringerDatabase
+		Replica  *MarshalerDatabase
+		Started  time.Time
+		Replicas map[string]StringerDatabase
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	config := Config{
+		Primary:  StringerDatabase{Host: "db.local", Password: "hunter2"},
+		Replica:  &MarshalerDatabase{Host: "replica.local", Token: "s3cr3t"},
+		Started:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Replicas: map[string]StringerDatabase{"eu": {Host: "eu.db.local", Password: "hunter3"}},
+	}
+	logger.Info("loaded", "config", LogValue(config))
+	expected := "level=INFO msg=loaded config.primary.host=db.local config.primary.password=REDACTED " +
+		"config.replica.host=replica.local config.replica.token=REDACTED config.started=2024-01-02T03:04:05.000Z " +
+		"config.replicas.eu.host=eu.db.local config.replicas.eu.password=REDACTED\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWrite_RedactsLocalizedSecrets(t *testing.T) {
+	type Config struct {
+		Token LocalizedString `secret:"true"`
+	}
+	config := &Config{Token: LocalizedString{Default: "s3cr3t", Locales: map[string]string{"de": "geheim"}}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, config); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if buf.String() != "token = REDACTED\n" {
+		t.Errorf("Expected the localized secret to be redacted, got:\n%s", buf.String())
+	}
+
+	aesgcm, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create AESGCM: %v", err)
+	}
+	buf.Reset()
+	if err := Write(&buf, config, WithEncrypter(aesgcm)); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "s3cr3t") || strings.Contains(output, "geheim") ||
+		!strings.HasPrefix(output, "token = enc:v1:") || !strings.Contains(output, "\ntoken[de] = enc:v1:") {
+		t.Errorf("Expected each localized value to be encrypted, got:\n%s", output)
+	}
+
+	parsed := &Config{}
+	if errs := Parse(&buf, parsed, WithArrayKeys(), WithDecrypter(aesgcm)); errs != nil {
+		t.Fatalf("Failed to parse written config: %v", errs)
+	}
+	if !reflect.DeepEqual(parsed, config) {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+}
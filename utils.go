@@ -2,8 +2,8 @@ package simpleini
 
 import (
 	"fmt"
-	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -45,11 +45,415 @@ func pascalToSnake(s string) string {
 	return result.String()
 }
 
-// substituteEnvVars replaces placeholders in the value with environment variable values.
-func substituteEnvVars(value string) string {
-	return os.Expand(value, func(key string) string {
-		return os.Getenv(key)
-	})
+// pascalToCamel converts a PascalCase string to camelCase.
+func pascalToCamel(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// substituteEnvVars replaces $NAME and ${NAME} placeholders in the value with
+// the values returned by lookup, and $$ with a literal $. Shell-style modifiers
+// are supported: ${NAME:-default} uses the default when the variable is unset
+// or empty, and ${NAME:?message} fails with the message instead. An unset
+// variable is empty, unless its name has a dot and no colon, in which case it
+// references another key and must be set.
+func substituteEnvVars(value string, lookup func(name string) (string, bool, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(value, '$')
+		if i < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		b.WriteString(value[:i])
+		value = value[i+1:]
+
+		var name, modifier, word string
+		switch {
+		case strings.HasPrefix(value, "$"):
+			b.WriteByte('$')
+			value = value[1:]
+			continue
+		case strings.HasPrefix(value, "{"):
+			end := strings.IndexByte(value, '}')
+			if end < 0 {
+				b.WriteString("$" + value)
+				return b.String(), nil
+			}
+			name = value[1:end]
+			value = value[end+1:]
+			if j := modifierIndex(name); j >= 0 {
+				name, modifier, word = name[:j], name[j:j+2], name[j+2:]
+			}
+		default:
+			n := 0
+			for n < len(value) && isWordByte(value[n]) {
+				n++
+			}
+			if n == 0 {
+				b.WriteByte('$')
+				continue
+			}
+			name, value = value[:n], value[n:]
+		}
+
+		resolved, ok, err := lookup(name)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case modifier == ":-" && resolved == "":
+			resolved = word
+		case modifier == ":?" && resolved == "":
+			if word == "" {
+				word = "parameter not set"
+			}
+			return "", fmt.Errorf("%s: %s", name, word)
+		case !ok && strings.Contains(name, ".") && !strings.Contains(name, ":"):
+			return "", fmt.Errorf("unresolved reference '${%s}'", name)
+		}
+		b.WriteString(resolved)
+	}
+}
+
+// modifierIndex returns the index of the first :- or :? modifier in the name of
+// a ${...} placeholder, or -1 if there is none.
+func modifierIndex(name string) int {
+	for i := 0; i+1 < len(name); i++ {
+		if name[i] == ':' && (name[i+1] == '-' || name[i+1] == '?') {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitInlineComment splits a line into its content and a trailing comment.
+// A comment starts at one of the prefixes when it is preceded by whitespace and
+// is not inside a single- or double-quoted string. The returned comment includes
+// its prefix, and the content has trailing whitespace removed.
+func splitInlineComment(line string, prefixes []string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case (c == '"' || c == '\'') && (i == 0 || !isWordByte(line[i-1])):
+			if n := quotedLength(line[i:]); n > 0 {
+				i += n - 1 // Skip the quoted string
+			}
+		case i > 0 && (line[i-1] == ' ' || line[i-1] == '\t'):
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(line[i:], prefix) {
+					return strings.TrimRight(line[:i], " \t"), line[i:]
+				}
+			}
+		}
+	}
+	return line, ""
+}
+
+// quotedLength returns the length of the single- or double-quoted string at the
+// start of s, including its quotes, or 0 if s does not start with a closed quote.
+func quotedLength(s string) int {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return 0
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case s[0]:
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// isQuoted reports whether the whole value is a single- or double-quoted string.
+func isQuoted(value string) bool {
+	return value != "" && quotedLength(value) == len(value)
+}
+
+// unquoteValue removes the quotes from a single- or double-quoted value and
+// interprets the escape sequences \n, \t, \r, \", \', \\ and \uXXXX.
+// Values that are not wholly quoted, such as "Hello" world or '90s, are
+// returned unchanged.
+func unquoteValue(value string) (string, error) {
+	if !isQuoted(value) {
+		return value, nil
+	}
+	var result strings.Builder
+	for i := 1; i < len(value)-1; i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			i++
+			switch value[i] {
+			case 'n':
+				result.WriteByte('\n')
+			case 't':
+				result.WriteByte('\t')
+			case 'r':
+				result.WriteByte('\r')
+			case '"', '\'', '\\':
+				result.WriteByte(value[i])
+			case 'u':
+				if i+4 >= len(value) {
+					return "", fmt.Errorf("invalid unicode escape in quoted value: %s", value)
+				}
+				code, err := strconv.ParseUint(value[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", fmt.Errorf("invalid unicode escape in quoted value: %s", value)
+				}
+				result.WriteRune(rune(code))
+				i += 4
+			default:
+				return "", fmt.Errorf("invalid escape sequence '\\%c' in quoted value: %s", value[i], value)
+			}
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String(), nil
+}
+
+// splitList splits a value into elements on the separator and on newlines,
+// ignoring separators inside quotes or escaped with a backslash. Each element
+// is trimmed and unquoted, and elements that are empty before unquoting are
+// dropped.
+func splitList(value, sep string) ([]string, error) {
+	var raw []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case (c == '"' || c == '\'') && strings.TrimSpace(value[start:i]) == "":
+			if n := quotedLength(value[i:]); n > 0 {
+				i += n - 1 // Skip the quoted element
+			}
+		case c == '\\' && strings.HasPrefix(value[i+1:], sep):
+			i += len(sep) // Skip the escaped separator
+		case c == '\n':
+			raw = append(raw, value[start:i])
+			start = i + 1
+		case strings.HasPrefix(value[i:], sep):
+			raw = append(raw, value[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	raw = append(raw, value[start:])
+
+	elements := make([]string, 0, len(raw))
+	for _, element := range raw {
+		element = strings.TrimSpace(element)
+		if element == "" {
+			continue
+		}
+		if !isQuoted(element) {
+			elements = append(elements, strings.ReplaceAll(element, "\\"+sep, sep))
+			continue
+		}
+		element, err := unquoteValue(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// joinList joins elements with the separator, quoting any element that needs
+// quoting or contains the separator or a backslash, which splitList would read
+// as escaping the separator. It is the inverse of splitList.
+func joinList(elements []string, sep string) string {
+	quoted := make([]string, len(elements))
+	for i, element := range elements {
+		if element == "" || strings.Contains(element, sep) || strings.Contains(element, "\\") {
+			quoted[i] = quoteString(element)
+		} else {
+			quoted[i] = quoteValue(element)
+		}
+	}
+	if strings.TrimSpace(sep) != "" {
+		sep = strings.TrimSpace(sep) + " "
+	}
+	return strings.Join(quoted, sep)
+}
+
+// joinTerminatedList joins the elements with the separator after each one, as in
+// a;b;c; in desktop entries. Separators inside elements are escaped with a
+// backslash.
+func joinTerminatedList(elements []string, sep string) string {
+	var b strings.Builder
+	for _, element := range elements {
+		b.WriteString(strings.ReplaceAll(element, sep, "\\"+sep))
+		b.WriteString(sep)
+	}
+	return b.String()
+}
+
+// needsQuoting reports whether a value must be quoted to be read back unchanged.
+func needsQuoting(value string) bool {
+	if value == "" {
+		return false
+	}
+	if value != strings.TrimSpace(value) || value[0] == '"' || value[0] == '\'' {
+		return true
+	}
+	for _, r := range value {
+		if r == ';' || r == '#' || unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteValue returns the value in double quotes if it needs quoting.
+func quoteValue(value string) string {
+	if !needsQuoting(value) {
+		return value
+	}
+	return quoteString(value)
+}
+
+// quoteString returns the value in double quotes, escaping any characters that
+// unquoteValue would interpret.
+func quoteString(value string) string {
+	var result strings.Builder
+	result.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"', '\\':
+			result.WriteByte('\\')
+			result.WriteRune(r)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\t':
+			result.WriteString(`\t`)
+		case '\r':
+			result.WriteString(`\r`)
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&result, `\u%04x`, r)
+			} else {
+				result.WriteRune(r)
+			}
+		}
+	}
+	result.WriteByte('"')
+	return result.String()
+}
+
+// isWordByte reports whether the byte is an ASCII letter, digit or underscore.
+// A quote that follows a word byte, as in "it's", is treated as a literal character.
+func isWordByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// splitArrayKey splits a PHP-style array key such as key[] or key[name] into the
+// key and its index. It reports false if the key has no index.
+func splitArrayKey(key string) (string, string, bool) {
+	open := strings.IndexByte(key, '[')
+	if open < 0 || !strings.HasSuffix(key, "]") {
+		return key, "", false
+	}
+	index := strings.TrimSpace(key[open+1 : len(key)-1])
+	if strings.ContainsAny(index, "[]") {
+		return key, "", false
+	}
+	return strings.TrimSpace(key[:open]), index, true
+}
+
+// splitSection splits a section name into the parts used to traverse the config
+// struct. A trailing quoted subsection, as in remote "origin", is kept as a
+// single part even if it contains dots.
+func splitSection(section string) []string {
+	name, quoted, found := strings.Cut(section, " \"")
+	parts := strings.Split(name, ".")
+	if found {
+		if subsection, err := unquoteValue(`"` + quoted); err == nil {
+			parts = append(parts, subsection)
+		}
+	}
+	return parts
+}
+
+// isSectionMap reports whether the type is a map with string keys and struct
+// values, which is populated from one section per map key.
+func isSectionMap(t reflect.Type) bool {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// indirectType returns the element type of a pointer type, or the type itself.
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// normalizeBool maps the words yes, on, no and off, and the empty string, onto
+// values understood by strconv.ParseBool. Other values are returned unchanged.
+func normalizeBool(value string) string {
+	switch strings.ToLower(value) {
+	case "yes", "on", "true":
+		return "true"
+	case "no", "off", "false", "":
+		return "false"
+	}
+	return value
+}
+
+// looseEqual reports whether two names are equal ignoring case, underscores,
+// dashes and spaces, so that defaultbranch matches DefaultBranch and
+// "desktop entry" matches DesktopEntry.
+func looseEqual(a, b string) bool {
+	strip := strings.NewReplacer("_", "", "-", "", " ", "")
+	return strings.EqualFold(strip.Replace(a), strip.Replace(b))
+}
+
+// fieldTagName returns the name in the ini tag of the field, without options
+// such as the secret in ini:"password,secret".
+func fieldTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("ini"), ",")
+	return name
+}
+
+// hasTagOption checks if the ini tag of the field has the option after its name.
+func hasTagOption(field reflect.StructField, option string) bool {
+	_, options, _ := strings.Cut(field.Tag.Get("ini"), ",")
+	for options != "" {
+		var opt string
+		opt, options, _ = strings.Cut(options, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOptionValues returns the values of an option of the ini tag of the field
+// that may be repeated, as in alias=a,alias=b.
+func tagOptionValues(field reflect.StructField, option string) []string {
+	_, options, _ := strings.Cut(field.Tag.Get("ini"), ",")
+	var values []string
+	for options != "" {
+		var opt string
+		opt, options, _ = strings.Cut(options, ",")
+		if value, ok := strings.CutPrefix(opt, option+"="); ok {
+			values = append(values, value)
+		}
+	}
+	return values
 }
 
 // isValidKey checks if the key contains only valid characters and is not empty.
@@ -65,6 +469,20 @@ func isValidKey(s string) bool {
 	return true
 }
 
+// isValidFreeformSection checks if the section is not empty and contains no
+// brackets or control characters. Spaces are allowed, as in [Desktop Entry].
+func isValidFreeformSection(s string) bool {
+	if strings.TrimSpace(s) == "" {
+		return false
+	}
+	for _, r := range s {
+		if r == '[' || r == ']' || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
 // isValidSection checks if the section contains only valid characters and is not empty.
 func isValidSection(s string) bool {
 	if s == "" {
@@ -1,7 +1,6 @@
 package simpleini
 
 import (
-	"os"
 	"reflect"
 	"testing"
 )
@@ -45,27 +44,306 @@ func TestPascalToSnake(t *testing.T) {
 }
 
 func TestSubstituteEnvVars(t *testing.T) {
-	os.Setenv("TEST_ENV_VAR", "test_value")
-	defer os.Unsetenv("TEST_ENV_VAR")
+	vars := map[string]string{"TEST_ENV_VAR": "test_value", "EMPTY_VAR": "", "paths.base": "/srv"}
+	lookup := func(name string) (string, bool, error) {
+		value, ok := vars[name]
+		return value, ok, nil
+	}
 
 	tests := []struct {
 		input    string
 		expected string
+		hasError bool
 	}{
-		{"${TEST_ENV_VAR}", "test_value"},
-		{"prefix_${TEST_ENV_VAR}_suffix", "prefix_test_value_suffix"},
-		{"no_env_var", "no_env_var"},
-		{"${NON_EXISTENT_VAR}", ""},
+		{"${TEST_ENV_VAR}", "test_value", false},
+		{"prefix_${TEST_ENV_VAR}_suffix", "prefix_test_value_suffix", false},
+		{"$TEST_ENV_VAR/bin", "test_value/bin", false},
+		{"no_env_var", "no_env_var", false},
+		{"${NON_EXISTENT_VAR}", "", false},
+		{"${NON_EXISTENT_VAR:-fallback}", "fallback", false},
+		{"${EMPTY_VAR:-fallback}", "fallback", false},
+		{"${TEST_ENV_VAR:-fallback}", "test_value", false},
+		{"${TEST_ENV_VAR:?must be set}", "test_value", false},
+		{"${NON_EXISTENT_VAR:?must be set}", "", true},
+		{"${EMPTY_VAR:?}", "", true},
+		{"cost: $$5 and $${TEST_ENV_VAR}", "cost: $5 and ${TEST_ENV_VAR}", false},
+		{"trailing $", "trailing $", false},
+		{"${unterminated", "${unterminated", false},
+		{"${paths.base}/logs", "/srv/logs", false},
+		{"${paths.missing}", "", true},
+		{"${paths.missing:-/tmp}", "/tmp", false},
 	}
 
 	for _, test := range tests {
-		result := substituteEnvVars(test.input)
+		result, err := substituteEnvVars(test.input, lookup)
+		if (err != nil) != test.hasError {
+			t.Errorf("substituteEnvVars(%q) error = %v; expected error = %v", test.input, err, test.hasError)
+		}
 		if result != test.expected {
 			t.Errorf("substituteEnvVars(%q) = %q; expected %q", test.input, result, test.expected)
 		}
 	}
 }
 
+func TestSplitInlineComment(t *testing.T) {
+	tests := []struct {
+		input   string
+		value   string
+		comment string
+	}{
+		{"key = value ; comment", "key = value", "; comment"},
+		{"key = value # comment", "key = value", "# comment"},
+		{"key = value;no_space", "key = value;no_space", ""},
+		{`key = "a ; b" ; c`, `key = "a ; b"`, "; c"},
+		{`key = 'a # b' # c`, `key = 'a # b'`, "# c"},
+		{`key = "a \" ; b" ; c`, `key = "a \" ; b"`, "; c"},
+		{"key = it's ; c", "key = it's", "; c"},
+		{"key = '90s ; c", "key = '90s", "; c"},
+		{"[section]	; c", "[section]", "; c"},
+		{"no comment", "no comment", ""},
+	}
+
+	for _, test := range tests {
+		value, comment := splitInlineComment(test.input, []string{";", "#"})
+		if value != test.value || comment != test.comment {
+			t.Errorf("splitInlineComment(%q) = (%q, %q); expected (%q, %q)", test.input, value, comment, test.value, test.comment)
+		}
+	}
+
+	if value, comment := splitInlineComment("key = value ; comment", nil); value != "key = value ; comment" || comment != "" {
+		t.Errorf("splitInlineComment with no prefixes = (%q, %q); expected the line unchanged", value, comment)
+	}
+}
+
+func TestUnquoteValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		hasError bool
+	}{
+		{"plain value", "plain value", false},
+		{`""`, "", false},
+		{`" padded "`, " padded ", false},
+		{`"a\nb\tc\rd"`, "a\nb\tc\rd", false},
+		{`"quote \" and \\"`, `quote " and \`, false},
+		{`'single \' quote'`, "single ' quote", false},
+		{`"\u00e9\u2603"`, "\u00e9\u2603", false},
+		{`C:\path\to`, `C:\path\to`, false},
+		{`"unterminated`, `"unterminated`, false},
+		{`'90s`, `'90s`, false},
+		{`"trailing\"`, `"trailing\"`, false},
+		{`"a"b`, `"a"b`, false},
+		{`"Hello" world`, `"Hello" world`, false},
+		{`"bad \x"`, "", true},
+		{`"\uZZZZ"`, "", true},
+	}
+
+	for _, test := range tests {
+		result, err := unquoteValue(test.input)
+		if (err != nil) != test.hasError {
+			t.Errorf("unquoteValue(%q) error = %v; expected error = %v", test.input, err, test.hasError)
+		}
+		if result != test.expected {
+			t.Errorf("unquoteValue(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestQuoteValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{`C:\path`, `C:\path`},
+		{" padded ", `" padded "`},
+		{"a;b", `"a;b"`},
+		{"a#b", `"a#b"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"tab\there", `"tab\there"`},
+		{`"quoted"`, `"\"quoted\""`},
+		{`'single`, `"'single"`},
+		{"bell\a", `"bell\u0007"`},
+		{`back\slash;`, `"back\\slash;"`},
+	}
+
+	for _, test := range tests {
+		result := quoteValue(test.input)
+		if result != test.expected {
+			t.Errorf("quoteValue(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+		unquoted, err := unquoteValue(result)
+		if err != nil || unquoted != test.input {
+			t.Errorf("unquoteValue(quoteValue(%q)) = %q, %v; expected the original value", test.input, unquoted, err)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		input    string
+		sep      string
+		expected []string
+		hasError bool
+	}{
+		{"a, b, c", ",", []string{"a", "b", "c"}, false},
+		{"a,,b,", ",", []string{"a", "b"}, false},
+		{`"a, b", 'c', ""`, ",", []string{"a, b", "c", ""}, false},
+		{`it's, fine`, ",", []string{"it's", "fine"}, false},
+		{"a :: b", "::", []string{"a", "b"}, false},
+		{"a, b\nc", ",", []string{"a", "b", "c"}, false},
+		{"", ",", []string{}, false},
+		{`"a\", b"`, ",", []string{`a", b`}, false},
+		{`a\;b;c;`, ";", []string{"a;b", "c"}, false},
+		{`"open, b`, ",", []string{`"open`, "b"}, false},
+		{`'90s, b`, ",", []string{"'90s", "b"}, false},
+		{`"a\q", b`, ",", nil, true},
+	}
+
+	for _, test := range tests {
+		result, err := splitList(test.input, test.sep)
+		if (err != nil) != test.hasError {
+			t.Errorf("splitList(%q) error = %v; expected error = %v", test.input, err, test.hasError)
+		}
+		if !test.hasError && !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("splitList(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestJoinList(t *testing.T) {
+	tests := []struct {
+		input    []string
+		sep      string
+		expected string
+	}{
+		{[]string{"a", "b"}, ",", "a, b"},
+		{[]string{"a, b", "c"}, ",", `"a, b", c`},
+		{[]string{"", " x "}, ",", `"", " x "`},
+		{[]string{"a", "b c"}, " ", `a "b c"`},
+		{[]string{`a\`, "b"}, ",", `"a\\", b`},
+		{[]string{`C:\dir`, "b"}, ";", `"C:\\dir"; b`},
+		{nil, ",", ""},
+	}
+
+	for _, test := range tests {
+		result := joinList(test.input, test.sep)
+		if result != test.expected {
+			t.Errorf("joinList(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+		elements, err := splitList(result, test.sep)
+		if err != nil || (len(test.input) > 0 && !reflect.DeepEqual(elements, test.input)) {
+			t.Errorf("splitList(joinList(%q)) = %q, %v; expected the original elements", test.input, elements, err)
+		}
+	}
+}
+
+func TestSplitArrayKey(t *testing.T) {
+	tests := []struct {
+		input   string
+		key     string
+		index   string
+		indexed bool
+	}{
+		{"key", "key", "", false},
+		{"key[]", "key", "", true},
+		{"key[0]", "key", "0", true},
+		{"key [ Name ]", "key", "Name", true},
+		{"key[a][b]", "key[a][b]", "", false},
+		{"key]", "key]", "", false},
+	}
+
+	for _, test := range tests {
+		key, index, indexed := splitArrayKey(test.input)
+		if key != test.key || index != test.index || indexed != test.indexed {
+			t.Errorf("splitArrayKey(%q) = (%q, %q, %v); expected (%q, %q, %v)", test.input, key, index, indexed, test.key, test.index, test.indexed)
+		}
+	}
+}
+
+func TestPascalToCamel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"DefaultBranch", "defaultBranch"},
+		{"Name", "name"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		result := pascalToCamel(test.input)
+		if result != test.expected {
+			t.Errorf("pascalToCamel(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestSplitSection(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"server", []string{"server"}},
+		{"server.logging.file", []string{"server", "logging", "file"}},
+		{`remote "origin"`, []string{"remote", "origin"}},
+		{`branch "feature/x.y"`, []string{"branch", "feature/x.y"}},
+		{`branch "say \"hi\""`, []string{"branch", `say "hi"`}},
+	}
+
+	for _, test := range tests {
+		result := splitSection(test.input)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("splitSection(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestIsSectionMap(t *testing.T) {
+	type section struct{ Name string }
+	tests := []struct {
+		value    interface{}
+		expected bool
+	}{
+		{map[string]section{}, true},
+		{map[string]*section{}, true},
+		{map[string]string{}, false},
+		{map[int]section{}, false},
+		{section{}, false},
+	}
+
+	for _, test := range tests {
+		result := isSectionMap(reflect.TypeOf(test.value))
+		if result != test.expected {
+			t.Errorf("isSectionMap(%T) = %v; expected %v", test.value, result, test.expected)
+		}
+	}
+}
+
+func TestNormalizeBool(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yes", "true"},
+		{"On", "true"},
+		{"TRUE", "true"},
+		{"no", "false"},
+		{"off", "false"},
+		{"", "false"},
+		{"1", "1"},
+		{"maybe", "maybe"},
+	}
+
+	for _, test := range tests {
+		result := normalizeBool(test.input)
+		if result != test.expected {
+			t.Errorf("normalizeBool(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
 func TestIsValidKey(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -161,3 +439,60 @@ func TestIsSupportedType(t *testing.T) {
 		}
 	}
 }
+
+func TestJoinTerminatedList(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected string
+	}{
+		{[]string{"a", "b"}, "a;b;"},
+		{[]string{"a;b"}, `a\;b;`},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		result := joinTerminatedList(test.input, ";")
+		if result != test.expected {
+			t.Errorf("joinTerminatedList(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestLooseEqual(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"default_branch", "DefaultBranch", true},
+		{"Desktop Entry", "DesktopEntry", true},
+		{"remain-after-exit", "RemainAfterExit", true},
+		{"name", "names", false},
+	}
+
+	for _, test := range tests {
+		result := looseEqual(test.a, test.b)
+		if result != test.expected {
+			t.Errorf("looseEqual(%q, %q) = %v; expected %v", test.a, test.b, result, test.expected)
+		}
+	}
+}
+
+func TestIsValidFreeformSection(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"Desktop Entry", true},
+		{"Desktop Action new-window", true},
+		{"a]b", false},
+		{"tab\there", false},
+		{" ", false},
+	}
+
+	for _, test := range tests {
+		result := isValidFreeformSection(test.input)
+		if result != test.expected {
+			t.Errorf("isValidFreeformSection(%q) = %v; expected %v", test.input, result, test.expected)
+		}
+	}
+}
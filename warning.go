@@ -0,0 +1,35 @@
+package simpleini
+
+import "fmt"
+
+// Warning describes a problem in the input that does not stop it from being
+// read, such as a repeated or deprecated key. Warnings are passed to the
+// handler set with WithWarningHandler, separately from the errors returned by
+// Parse.
+type Warning struct {
+	Line    int    // line of the input the warning refers to
+	Section string // section of the key, or the repeated section
+	Key     string // key as written in the input, or empty for a section
+	Message string // description of the problem
+}
+
+// String returns the warning in the format of errors, as in "warning at line
+// 3: duplicate key 'port', first set at line 2".
+func (w Warning) String() string {
+	return fmt.Sprintf("warning at line %d: %s", w.Line, w.Message)
+}
+
+// WithWarningHandler calls the handler for each warning while the input is
+// read. Without a handler, warnings are ignored.
+func WithWarningHandler(handler func(Warning)) Option {
+	return func(o *options) {
+		o.warningHandler = handler
+	}
+}
+
+// warn passes the warning to the handler.
+func (d *decoder) warn(w Warning) {
+	if d.opts.warningHandler != nil {
+		d.opts.warningHandler(w)
+	}
+}
@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 )
 
+// encoder holds the settings for a single Write.
+type encoder struct {
+	w    io.Writer
+	opts options
+}
+
 // Write writes the config struct to the provided io.Writer in INI format.
-func Write(w io.Writer, config interface{}) error {
+func Write(w io.Writer, config interface{}, opts ...Option) error {
 	fieldCache = sync.Map{} // Clear the field cache
 
 	v := reflect.ValueOf(config)
@@ -19,42 +26,43 @@ func Write(w io.Writer, config interface{}) error {
 	}
 	v = v.Elem()
 
-	return writeStruct(w, v, "")
+	e := &encoder{w: w, opts: newOptions(opts)}
+	return e.writeStruct(v, "")
 }
 
-func writeStruct(w io.Writer, v reflect.Value, section string) error {
-	return writeStructHelper(w, v, section, false)
+func (e *encoder) writeStruct(v reflect.Value, section string) error {
+	return e.writeStructHelper(v, section, false)
 }
 
-func writeStructAsComments(w io.Writer, v reflect.Value, section string) error {
-	return writeStructHelper(w, v, section, true)
+func (e *encoder) writeStructAsComments(v reflect.Value, section string) error {
+	return e.writeStructHelper(v, section, true)
 }
 
-func writeStructHelper(w io.Writer, v reflect.Value, section string, asComments bool) error {
-	if err := writeFields(w, v, section, asComments); err != nil {
+func (e *encoder) writeStructHelper(v reflect.Value, section string, asComments bool) error {
+	if err := e.writeFields(v, section, asComments); err != nil {
 		return err
 	}
 
-	return writeNestedStructs(w, v, section, asComments)
+	return e.writeNestedStructs(v, section, asComments)
 }
 
-func writeFields(w io.Writer, v reflect.Value, section string, asComments bool) error {
+func (e *encoder) writeFields(v reflect.Value, section string, asComments bool) error {
 	t := v.Type()
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		fieldValue := v.Field(i)
-		tagName := field.Tag.Get("ini")
+		tagName := fieldTagName(field)
 		if tagName == "" {
-			tagName = pascalToSnake(field.Name)
+			tagName = e.opts.nameFunc(field.Name)
 		}
 		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
-			if err := writeFields(w, fieldValue, section, asComments); err != nil {
+			if err := e.writeFields(fieldValue, section, asComments); err != nil {
 				return err
 			}
 			continue
 		}
-		if err := writeField(w, fieldValue, tagName, section, asComments); err != nil {
+		if err := e.writeField(field, fieldValue, tagName, section, asComments); err != nil {
 			return err
 		}
 	}
@@ -62,11 +70,27 @@ func writeFields(w io.Writer, v reflect.Value, section string, asComments bool)
 	return nil
 }
 
-func writeField(w io.Writer, fieldValue reflect.Value, tagName, section string, asComments bool) error {
-	if fieldValue.Kind() == reflect.Struct || (fieldValue.Kind() == reflect.Ptr && fieldValue.Type().Elem().Kind() == reflect.Struct) {
+func (e *encoder) writeField(field reflect.StructField, fieldValue reflect.Value, tagName, section string, asComments bool) error {
+	localized := isLocalizedField(field.Type, e.opts.localizedKeys)
+	if !localized && (fieldValue.Kind() == reflect.Struct || (fieldValue.Kind() == reflect.Ptr && fieldValue.Type().Elem().Kind() == reflect.Struct) || isSectionMap(fieldValue.Type())) {
 		return nil
 	}
 
+	secret := isSecret(field) && !fieldValue.IsZero()
+	if secret && e.opts.encrypter == nil && !e.opts.revealSecrets {
+		return e.writeKeyValue(strings.TrimPrefix(tagName, section+"."), RedactedValue, asComments)
+	}
+	encrypt := secret && e.opts.encrypter != nil
+	if localized {
+		return e.writeLocalizedField(fieldValue, tagName, section, encrypt, asComments)
+	}
+	if sep := fieldSeparator(field, e.opts.sliceSeparator); sep != "" {
+		return e.writeListField(fieldValue, tagName, section, sep, encrypt, asComments)
+	}
+	if e.opts.appendSlices && isAppendable(field.Type) {
+		return e.writeRepeatedField(fieldValue, tagName, section, encrypt, asComments)
+	}
+
 	if (fieldValue.Kind() == reflect.Ptr && !isSupportedType(fieldValue.Type().Elem().Kind())) || (fieldValue.Kind() != reflect.Ptr && !isSupportedType(fieldValue.Kind())) {
 		return fmt.Errorf("unsupported field type: %s", fieldValue.Kind())
 	}
@@ -86,52 +110,219 @@ func writeField(w io.Writer, fieldValue reflect.Value, tagName, section string,
 		value = fmt.Sprintf("%v", fieldValue.Interface())
 	}
 
+	if encrypt {
+		return e.writeEncryptedValue(tagName, value, asComments)
+	}
+	return e.writeKeyValue(tagName, e.quoteValue(value), asComments)
+}
+
+// writeEncryptedValue writes the value encrypted as an enc:v1: value.
+func (e *encoder) writeEncryptedValue(key, value string, asComments bool) error {
+	encrypted, err := EncryptValue(e.opts.encrypter, value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt value of '%s': %w", key, err)
+	}
+	return e.writeKeyValue(key, encrypted, asComments)
+}
+
+// quoteValue quotes the value if it needs quoting, unless values are raw.
+func (e *encoder) quoteValue(value string) string {
+	if e.opts.rawValues {
+		return value
+	}
+	if e.continuesLine(value) {
+		return quoteString(value)
+	}
+	return quoteValue(value)
+}
+
+// continuesLine reports whether the value ends with a backslash that would be
+// read back as continuing the value on the next line.
+func (e *encoder) continuesLine(value string) bool {
+	return e.opts.continuation&ContinuationBackslash != 0 && strings.HasSuffix(value, "\\")
+}
+
+// writeKeyValue writes a single key-value line, or the key alone as a comment.
+func (e *encoder) writeKeyValue(key, value string, asComments bool) error {
 	if asComments {
-		_, err := fmt.Fprintf(w, "; %s %s\n", tagName, delimiter)
+		_, err := fmt.Fprintf(e.w, "; %s %s\n", key, e.opts.delimiter)
 		return err
 	}
-	_, err := fmt.Fprintf(w, "%s %s %s\n", tagName, delimiter, value)
+	// Quoted values are always safe, but raw values cannot be quoted, so some of
+	// them would not be read back
+	switch {
+	case strings.ContainsAny(value, "\r\n"):
+		return fmt.Errorf("cannot write value of '%s': it contains a line break", key)
+	case e.continuesLine(value):
+		return fmt.Errorf("cannot write value of '%s': a trailing backslash would continue it on the next line", key)
+	}
+	indent, space := "", " "
+	if e.opts.indentKeys {
+		indent = "\t"
+	}
+	if e.opts.tightDelimiter {
+		space = ""
+	}
+	_, err := fmt.Fprintf(e.w, "%s%s%s%s%s%s\n", indent, key, space, e.opts.delimiter, space, e.escapeValue(value))
 	return err
 }
 
-func writeNestedStructs(w io.Writer, v reflect.Value, section string, asComments bool) error {
+// escapeValue escapes the characters that would be expanded when the value is
+// read back: $ as $$, which is a literal $ whether or not expansion is enabled,
+// and % as %% with basic interpolation.
+func (e *encoder) escapeValue(value string) string {
+	value = strings.ReplaceAll(value, "$", "$$")
+	if e.opts.interpolation == InterpolationBasic {
+		value = strings.ReplaceAll(value, "%", "%%")
+	}
+	return value
+}
+
+// writeListField writes a slice field with a sep tag on a single line, joining
+// the elements with the separator and optionally encrypting the joined value.
+func (e *encoder) writeListField(fieldValue reflect.Value, tagName, section, sep string, encrypt, asComments bool) error {
+	if fieldValue.Kind() == reflect.Ptr {
+		fieldValue = fieldValue.Elem()
+	}
+	if fieldValue.IsValid() {
+		if elemKind := fieldValue.Type().Elem().Kind(); !isSupportedType(elemKind) {
+			return fmt.Errorf("unsupported field type: []%s", elemKind)
+		}
+	}
+
+	if section != "" {
+		tagName = strings.TrimPrefix(tagName, section+".")
+	}
+
+	var elements []string
+	if fieldValue.IsValid() {
+		for i := 0; i < fieldValue.Len(); i++ {
+			elements = append(elements, fmt.Sprintf("%v", fieldValue.Index(i).Interface()))
+		}
+	}
+	var value string
+	if e.opts.terminatedLists {
+		value = joinTerminatedList(elements, sep)
+	} else {
+		value = joinList(elements, sep)
+	}
+	if encrypt {
+		return e.writeEncryptedValue(tagName, value, asComments)
+	}
+	return e.writeKeyValue(tagName, value, asComments)
+}
+
+// writeRepeatedField writes a slice field as one key-value line per element,
+// optionally encrypting each element.
+func (e *encoder) writeRepeatedField(fieldValue reflect.Value, tagName, section string, encrypt, asComments bool) error {
+	if fieldValue.Kind() == reflect.Ptr {
+		fieldValue = fieldValue.Elem()
+	}
+	if !fieldValue.IsValid() {
+		return nil
+	}
+	if elemKind := fieldValue.Type().Elem().Kind(); !isSupportedType(elemKind) {
+		return fmt.Errorf("unsupported field type: []%s", elemKind)
+	}
+
+	if section != "" {
+		tagName = strings.TrimPrefix(tagName, section+".")
+	}
+
+	for i := 0; i < fieldValue.Len(); i++ {
+		value := fmt.Sprintf("%v", fieldValue.Index(i).Interface())
+		var err error
+		if encrypt {
+			err = e.writeEncryptedValue(tagName, value, asComments)
+		} else {
+			err = e.writeKeyValue(tagName, e.quoteValue(value), asComments)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLocalizedField writes the default value of a LocalizedString or a map of
+// strings keyed by locale, followed by one key[locale] line per translation.
+func (e *encoder) writeLocalizedField(fieldValue reflect.Value, tagName, section string, encrypt, asComments bool) error {
+	if fieldValue.Kind() == reflect.Ptr {
+		if fieldValue.IsNil() {
+			return nil
+		}
+		fieldValue = fieldValue.Elem()
+	}
+
+	if section != "" {
+		tagName = strings.TrimPrefix(tagName, section+".")
+	}
+
+	def, locales, values := localizedEntries(fieldValue)
+	if err := e.writeLocalizedValue(tagName, def, encrypt, asComments); err != nil {
+		return err
+	}
+	for _, locale := range locales {
+		if err := e.writeLocalizedValue(tagName+"["+locale+"]", values[locale], encrypt, asComments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLocalizedValue writes one value of a localized field, optionally encrypting it.
+func (e *encoder) writeLocalizedValue(key, value string, encrypt, asComments bool) error {
+	if encrypt {
+		return e.writeEncryptedValue(key, value, asComments)
+	}
+	return e.writeKeyValue(key, e.quoteValue(value), asComments)
+}
+
+func (e *encoder) writeNestedStructs(v reflect.Value, section string, asComments bool) error {
 	t := v.Type()
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		fieldValue := v.Field(i)
-		tagName := field.Tag.Get("ini")
+		tagName := fieldTagName(field)
 		if tagName == "" {
-			tagName = pascalToSnake(field.Name)
+			tagName = e.opts.nameFunc(field.Name)
+		}
+		if indirectType(field.Type) == localizedStringType {
+			continue
 		}
 		if fieldValue.Kind() == reflect.Struct && !field.Anonymous {
 			newSection := buildSectionName(section, tagName)
-			if err := writeSectionHeader(w, newSection, asComments); err != nil {
+			if err := e.writeSectionHeader(newSection, asComments); err != nil {
 				return err
 			}
-			if err := writeStructHelper(w, fieldValue, newSection, asComments); err != nil {
+			if err := e.writeStructHelper(fieldValue, newSection, asComments); err != nil {
 				return err
 			}
 		} else if fieldValue.Kind() == reflect.Ptr && fieldValue.Type().Elem().Kind() == reflect.Struct {
 			newSection := buildSectionName(section, tagName)
 
 			if fieldValue.IsNil() {
-				if err := writeSectionHeader(w, newSection, true); err != nil {
+				if err := e.writeSectionHeader(newSection, true); err != nil {
 					return err
 				}
-				if err := writeStructAsComments(w, reflect.New(field.Type.Elem()).Elem(), newSection); err != nil {
+				if err := e.writeStructAsComments(reflect.New(field.Type.Elem()).Elem(), newSection); err != nil {
 					return err
 				}
 			} else {
-				if err := writeSectionHeader(w, newSection, asComments); err != nil {
+				if err := e.writeSectionHeader(newSection, asComments); err != nil {
 					return err
 				}
-				if err := writeStructHelper(w, fieldValue.Elem(), newSection, asComments); err != nil {
+				if err := e.writeStructHelper(fieldValue.Elem(), newSection, asComments); err != nil {
 					return err
 				}
 			}
+		} else if isSectionMap(fieldValue.Type()) {
+			if err := e.writeSectionMap(fieldValue, buildSectionName(section, tagName), asComments); err != nil {
+				return err
+			}
 		} else if field.Anonymous && fieldValue.Kind() == reflect.Struct {
-			if err := writeNestedStructs(w, fieldValue, section, asComments); err != nil {
+			if err := e.writeNestedStructs(fieldValue, section, asComments); err != nil {
 				return err
 			}
 		}
@@ -140,6 +331,34 @@ func writeNestedStructs(w io.Writer, v reflect.Value, section string, asComments
 	return nil
 }
 
+// writeSectionMap writes one section per entry of a map of structs, sorted by key.
+func (e *encoder) writeSectionMap(m reflect.Value, section string, asComments bool) error {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	for _, key := range keys {
+		entry := m.MapIndex(key)
+		if entry.Kind() == reflect.Ptr {
+			if entry.IsNil() {
+				continue
+			}
+			entry = entry.Elem()
+		}
+
+		newSection := buildSectionName(section, key.String())
+		if e.opts.subsections {
+			newSection = section + " " + quoteString(key.String())
+		}
+		if err := e.writeSectionHeader(newSection, asComments); err != nil {
+			return err
+		}
+		if err := e.writeStructHelper(entry, newSection, asComments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func buildSectionName(section, tagName string) string {
 	if section == "" {
 		return tagName
@@ -147,11 +366,11 @@ func buildSectionName(section, tagName string) string {
 	return section + "." + tagName
 }
 
-func writeSectionHeader(w io.Writer, section string, asComments bool) error {
+func (e *encoder) writeSectionHeader(section string, asComments bool) error {
 	if asComments {
-		_, err := fmt.Fprintf(w, "\n; [%s]\n", section)
+		_, err := fmt.Fprintf(e.w, "\n; [%s]\n", section)
 		return err
 	}
-	_, err := fmt.Fprintf(w, "\n[%s]\n", section)
+	_, err := fmt.Fprintf(e.w, "\n[%s]\n", section)
 	return err
 }
@@ -3,6 +3,7 @@ package simpleini
 import (
 	"bytes"
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -549,3 +550,148 @@ func TestWrite_FprintfError(t *testing.T) {
 		t.Errorf("expected %s, got %s", expectedError, err.Error())
 	}
 }
+
+type QuotedConfig struct {
+	Name  string `ini:"name"`
+	Notes string `ini:"notes"`
+}
+
+func TestWrite_QuotedValues(t *testing.T) {
+	config := &QuotedConfig{
+		Name:  "  padded  ",
+		Notes: "a ; b\n\"c\"",
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, config); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `name = "  padded  "
+notes = "a ; b\n\"c\""
+`
+	if buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+
+	parsed := &QuotedConfig{}
+	if errs := Parse(&buf, parsed, WithInlineComments()); errs != nil {
+		t.Fatalf("expected no error parsing written config, got %v", errs)
+	}
+	if *parsed != *config {
+		t.Errorf("round trip mismatch: got %+v", parsed)
+	}
+}
+
+func TestWrite_EscapedExpansions(t *testing.T) {
+	type ExpansionConfig struct {
+		Cost  string   `ini:"cost"`
+		Price string   `ini:"price"`
+		Ref   string   `ini:"ref"`
+		Raw   string   `ini:"raw" expand:"false"`
+		Tags  []string `ini:"tags" sep:","`
+	}
+
+	config := &ExpansionConfig{
+		Cost:  "cost $5 and 10%",
+		Price: "price ${X}",
+		Ref:   "see ${paths.base} or $$",
+		Raw:   "raw ${HOME} $$",
+		Tags:  []string{"$a", "b"},
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected string
+	}{
+		{
+			"env expansion",
+			nil,
+			"cost = cost $$5 and 10%\nprice = price $${X}\nref = see $${paths.base} or $$$$\nraw = raw $${HOME} $$$$\ntags = $$a, b\n",
+		},
+		{
+			"no expansion",
+			[]Option{WithEnvExpansion(false)},
+			"cost = cost $$5 and 10%\nprice = price $${X}\nref = see $${paths.base} or $$$$\nraw = raw $${HOME} $$$$\ntags = $$a, b\n",
+		},
+		{
+			"basic interpolation",
+			[]Option{WithInterpolation(InterpolationBasic)},
+			"cost = cost $$5 and 10%%\nprice = price $${X}\nref = see $${paths.base} or $$$$\nraw = raw $${HOME} $$$$\ntags = $$a, b\n",
+		},
+		{
+			"extended interpolation",
+			[]Option{WithInterpolation(InterpolationExtended)},
+			"cost = cost $$5 and 10%\nprice = price $${X}\nref = see $${paths.base} or $$$$\nraw = raw $${HOME} $$$$\ntags = $$a, b\n",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, config, test.opts...); err != nil {
+			t.Fatalf("%s: expected no error, got %v", test.name, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, buf.String())
+		}
+
+		parsed := &ExpansionConfig{}
+		if errs := Parse(&buf, parsed, test.opts...); errs != nil {
+			t.Fatalf("%s: expected no error parsing written config, got %v", test.name, errs)
+		}
+		if !reflect.DeepEqual(parsed, config) {
+			t.Errorf("%s: round trip mismatch: got %+v", test.name, parsed)
+		}
+	}
+}
+
+func TestWrite_SeparatedSlices(t *testing.T) {
+	type ListConfig struct {
+		Hosts []string `ini:"hosts" sep:","`
+		Ports []uint   `ini:"ports" sep:","`
+		Tags  []string `ini:"tags" sep:" "`
+		Empty []string `ini:"empty" sep:","`
+	}
+
+	config := &ListConfig{
+		Hosts: []string{"alpha", "beta, gamma"},
+		Ports: []uint{80, 443},
+		Tags:  []string{"one", "two words"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, config); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := `hosts = alpha, "beta, gamma"
+ports = 80, 443
+tags = one "two words"
+empty = 
+`
+	if buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+
+	parsed := &ListConfig{}
+	if errs := Parse(&buf, parsed); errs != nil {
+		t.Fatalf("expected no error parsing written config, got %v", errs)
+	}
+	config.Empty = []string{}
+	if !reflect.DeepEqual(parsed, config) {
+		t.Errorf("round trip mismatch: got %+v", parsed)
+	}
+}
+
+func TestWrite_UnsupportedSliceElement(t *testing.T) {
+	type ListConfig struct {
+		Values []complex128 `ini:"values" sep:","`
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, &ListConfig{})
+	if err == nil || err.Error() != "unsupported field type: []complex128" {
+		t.Errorf("expected unsupported field type error, got %v", err)
+	}
+}